@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+    corev1 "k8s.io/api/core/v1"
     metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -12,6 +13,7 @@ import (
 // +kubebuilder:printcolumn:name="Max",type=integer,JSONPath=`.spec.maxReplicas`
 // +kubebuilder:printcolumn:name="Current",type=integer,JSONPath=`.status.currentReplicas`
 // +kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.desiredReplicas`
+// +kubebuilder:printcolumn:name="Override",type=string,JSONPath=`.status.override.source`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Mode defines how the controller should act on this autoscaler.
@@ -22,17 +24,164 @@ const (
     ModeDryRun Mode = "DryRun"
 )
 
-// PrometheusConfig configures how we talk to Prometheus for this autoscaler.
+// PausedAnnotation pauses an autoscaler when set to "true", like
+// spec.suspend, without editing the spec (e.g. during an incident while the
+// spec is managed by GitOps).
+const PausedAnnotation = "autoscaler.parspack.dev/paused"
+
+// OverrideSource tells what is currently overriding the policy engine.
+type OverrideSource string
+
+const (
+    OverrideSourceSuspend         OverrideSource = "Suspend"
+    OverrideSourcePauseAnnotation OverrideSource = "PauseAnnotation"
+    OverrideSourceOverride        OverrideSource = "Override"
+)
+
+// OnDeletePolicy decides what happens to the target when the autoscaler is
+// deleted.
+type OnDeletePolicy string
+
+const (
+    // OnDeleteRetain leaves the target at its current replicas.
+    OnDeleteRetain OnDeletePolicy = "Retain"
+
+    // OnDeleteRestore scales the target back to status.originalReplicas.
+    OnDeleteRestore OnDeletePolicy = "Restore"
+
+    // OnDeleteMinReplicas scales the target to spec.minReplicas.
+    OnDeleteMinReplicas OnDeletePolicy = "MinReplicas"
+)
+
+// PrometheusConfig configures how we talk to Prometheus for this autoscaler:
+// either inline, or by referencing a shared (Cluster)PrometheusEndpoint.
+// +kubebuilder:validation:XValidation:rule="has(self.url) != has(self.endpointRef)",message="exactly one of url and endpointRef must be set"
 type PrometheusConfig struct {
-    // URL is the base URL of the Prometheus HTTP API.
+    PrometheusConnection `json:",inline"`
+
+    // EndpointRef uses the connection settings of a PrometheusEndpoint in
+    // the autoscaler's namespace or of a ClusterPrometheusEndpoint.
+    // +optional
+    EndpointRef *EndpointReference `json:"endpointRef,omitempty"`
+}
+
+// EndpointKind names the kinds an EndpointReference can point to.
+type EndpointKind string
+
+const (
+    EndpointKindNamespaced EndpointKind = "PrometheusEndpoint"
+    EndpointKindCluster    EndpointKind = "ClusterPrometheusEndpoint"
+)
+
+// EndpointReference points to a PrometheusEndpoint or ClusterPrometheusEndpoint.
+type EndpointReference struct {
+    // Kind defaults to PrometheusEndpoint.
+    // +kubebuilder:validation:Enum=PrometheusEndpoint;ClusterPrometheusEndpoint
+    // +optional
+    Kind EndpointKind `json:"kind,omitempty"`
+
+    // +kubebuilder:validation:MinLength=1
+    Name string `json:"name"`
+}
+
+// EndpointStrategy decides how several Prometheus URLs are queried.
+type EndpointStrategy string
+
+const (
+    EndpointStrategyFailover EndpointStrategy = "Failover"
+    EndpointStrategyCompare  EndpointStrategy = "Compare"
+)
+
+// PrometheusConnection holds everything needed to reach a Prometheus
+// compatible API. It is shared by inline autoscaler configs and the
+// endpoint resources.
+type PrometheusConnection struct {
+    // URL is the base URL of the Prometheus HTTP API. It may carry a path
+    // prefix, e.g. http://mimir-query-frontend.mimir.svc:8080/prometheus
     // Example: http://prometheus.monitoring.svc.cluster.local:9090
     // +kubebuilder:validation:MinLength=1
-    URL string `json:"url"`
+    // +optional
+    URL string `json:"url,omitempty"`
+
+    // FailoverURLs are further replicas of the same Prometheus (e.g. the
+    // second member of an HA pair). They share every other setting.
+    // +optional
+    FailoverURLs []string `json:"failoverURLs,omitempty"`
+
+    // Strategy decides how URL and FailoverURLs are used. Failover (the
+    // default) tries them in order, skipping endpoints whose circuit
+    // breaker is open. Compare queries all of them and uses the highest
+    // value, so a replica with gaps after a restart cannot under-report.
+    // +kubebuilder:validation:Enum=Failover;Compare
+    // +optional
+    Strategy EndpointStrategy `json:"strategy,omitempty"`
+
+    // TenantID is sent as the X-Scope-OrgID header required by multi-tenant
+    // backends such as Mimir and Cortex.
+    // +optional
+    TenantID string `json:"tenantID,omitempty"`
+
+    // Headers are extra, non-secret headers added to every request.
+    // Credentials belong in AuthSecretRef, whose headers take precedence.
+    // +optional
+    Headers map[string]string `json:"headers,omitempty"`
+
+    // QueryParameters are added to every query, e.g. dedup=true and
+    // partial_response=false for Thanos Query.
+    // +optional
+    QueryParameters map[string]string `json:"queryParameters,omitempty"`
 
     // AuthSecretRef optionally points to a Secret containing auth details.
     // Keeping credentials out of the CR spec helps avoid accidental leaks.
     // +optional
     AuthSecretRef *string `json:"authSecretRef,omitempty"`
+
+    // TLS configures HTTPS towards Prometheus (private CA, mutual TLS).
+    // +optional
+    TLS *TLSConfig `json:"tls,omitempty"`
+
+    // TimeoutSeconds bounds each query attempt against this endpoint.
+    // Defaults to the controller's --prometheus-query-timeout.
+    // +optional
+    // +kubebuilder:validation:Minimum=1
+    TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// SecretOrConfigMapKeySelector selects a key from either a Secret or a
+// ConfigMap in the namespace of the referencing object (see
+// ClusterPrometheusEndpoint for cluster-scoped endpoints). Exactly one
+// should be set.
+type SecretOrConfigMapKeySelector struct {
+    // +optional
+    Secret *corev1.SecretKeySelector `json:"secret,omitempty"`
+
+    // +optional
+    ConfigMap *corev1.ConfigMapKeySelector `json:"configMap,omitempty"`
+}
+
+// TLSConfig configures the TLS client used to talk to Prometheus.
+type TLSConfig struct {
+    // CA is a PEM bundle used to verify the Prometheus server certificate.
+    // When unset, the system roots are used.
+    // +optional
+    CA *SecretOrConfigMapKeySelector `json:"ca,omitempty"`
+
+    // Cert is the PEM client certificate for mutual TLS.
+    // +optional
+    Cert *corev1.SecretKeySelector `json:"cert,omitempty"`
+
+    // KeySecret is the PEM private key matching Cert.
+    // +optional
+    KeySecret *corev1.SecretKeySelector `json:"keySecret,omitempty"`
+
+    // ServerName overrides the name used to verify the server certificate.
+    // +optional
+    ServerName string `json:"serverName,omitempty"`
+
+    // InsecureSkipVerify disables server certificate verification.
+    // Only meant for testing; it must be set explicitly.
+    // +optional
+    InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
 }
 
 // ScaleDirection defines thresholds and step sizes for scaling decisions.
@@ -48,15 +197,219 @@ type ScaleDirection struct {
     Step int32 `json:"step"`
 }
 
+// MetricTargetType selects how a target value is compared with the sample.
+type MetricTargetType string
+
+const (
+    // MetricTargetValue compares the raw sample with the target value.
+    MetricTargetValue MetricTargetType = "Value"
+
+    // MetricTargetAverageValue treats the sample as a total and the target
+    // as the desired value per replica (e.g. RPS per pod).
+    MetricTargetAverageValue MetricTargetType = "AverageValue"
+
+    // MetricTargetUtilization compares a percentage sample (0-100) with the
+    // target utilization percentage.
+    MetricTargetUtilization MetricTargetType = "Utilization"
+)
+
+// MetricTarget configures proportional, HPA-style scaling for one metric.
+// Desired replicas are computed from the ratio between the sample and the
+// target instead of adding or removing a fixed step.
+type MetricTarget struct {
+    // Type decides how the sample is interpreted against Value.
+    // +kubebuilder:validation:Enum=Value;AverageValue;Utilization
+    Type MetricTargetType `json:"type"`
+
+    // Value is the target: a raw value, a per-replica value, or a
+    // utilization percentage depending on Type.
+    Value float64 `json:"value"`
+
+    // Tolerance is the relative band around the target in which we do not
+    // scale at all. Defaults to 0.1 (10%), the same as the HPA.
+    // +optional
+    // +kubebuilder:validation:Minimum=0
+    Tolerance *float64 `json:"tolerance,omitempty"`
+}
+
+// MetricRole defines what a metric contributes to a scaling decision.
+type MetricRole string
+
+const (
+    // MetricRoleScale metrics vote on the desired replica count.
+    MetricRoleScale MetricRole = "scale"
+
+    // MetricRoleGate metrics never vote; they can only veto scaling when a
+    // downstream dependency (MySQL, Redis, ...) is saturated.
+    MetricRoleGate MetricRole = "gate"
+)
+
+// GateAction defines what happens while a gate metric is tripped.
+type GateAction string
+
+const (
+    // GateActionBlockScaleUp prevents scale-up but still allows scale-down.
+    GateActionBlockScaleUp GateAction = "BlockScaleUp"
+
+    // GateActionHold keeps the current replica count in both directions.
+    GateActionHold GateAction = "Hold"
+)
+
+// GateSpec configures a safety metric with role=gate.
+type GateSpec struct {
+    // Threshold trips the gate when the sample is above this value.
+    Threshold float64 `json:"threshold"`
+
+    // Action is applied while the gate is tripped. Defaults to BlockScaleUp.
+    // +kubebuilder:validation:Enum=BlockScaleUp;Hold
+    // +optional
+    Action GateAction `json:"action,omitempty"`
+}
+
+// MetricErrorAction decides how a metric contributes when its query fails.
+type MetricErrorAction string
+
+const (
+    // MetricErrorSkip treats the metric as neutral (votes for the current count).
+    MetricErrorSkip MetricErrorAction = "Skip"
+
+    // MetricErrorHold keeps the current replica count for the whole decision.
+    MetricErrorHold MetricErrorAction = "Hold"
+
+    // MetricErrorScaleToMax makes the metric vote for maxReplicas.
+    MetricErrorScaleToMax MetricErrorAction = "ScaleToMax"
+
+    // MetricErrorUseLastKnown reuses the last successful sample for a while,
+    // then falls back to Skip.
+    MetricErrorUseLastKnown MetricErrorAction = "UseLastKnown"
+)
+
+// MetricErrorPolicy configures what happens when a metric cannot be queried.
+type MetricErrorPolicy struct {
+    // Action defaults to Skip.
+    // +kubebuilder:validation:Enum=Skip;Hold;ScaleToMax;UseLastKnown
+    // +optional
+    Action MetricErrorAction `json:"action,omitempty"`
+
+    // LastKnownMaxAgeSeconds bounds how old the last known value may be when
+    // Action is UseLastKnown. Defaults to 300.
+    // +optional
+    // +kubebuilder:validation:Minimum=1
+    LastKnownMaxAgeSeconds *int32 `json:"lastKnownMaxAgeSeconds,omitempty"`
+}
+
+// NonFiniteAction decides what happens to a NaN or ±Inf sample.
+type NonFiniteAction string
+
+const (
+    // NonFiniteMissing rejects the sample; the metric's onError policy applies.
+    NonFiniteMissing NonFiniteAction = "Missing"
+
+    // NonFiniteReplace uses a configured value instead.
+    NonFiniteReplace NonFiniteAction = "Replace"
+)
+
+// NonFinitePolicy handles NaN and ±Inf samples, e.g. a ratio query dividing
+// by zero.
+type NonFinitePolicy struct {
+    // +kubebuilder:validation:Enum=Missing;Replace
+    Action NonFiniteAction `json:"action"`
+
+    // Value is used instead of the sample when Action is Replace.
+    // +optional
+    Value *float64 `json:"value,omitempty"`
+}
+
+// RangeReducer reduces the points of a range query to a single sample.
+type RangeReducer string
+
+const (
+    RangeReducerAvg   RangeReducer = "avg"
+    RangeReducerMax   RangeReducer = "max"
+    RangeReducerMin   RangeReducer = "min"
+    RangeReducerP90   RangeReducer = "p90"
+    RangeReducerP95   RangeReducer = "p95"
+    RangeReducerLast  RangeReducer = "last"
+    RangeReducerSlope RangeReducer = "slope"
+)
+
+// RangeQuerySpec evaluates the metric as a range query over a lookback
+// window and reduces the points before they reach the policy engine.
+// This avoids expensive PromQL subqueries such as quantile_over_time(...[5m:]).
+type RangeQuerySpec struct {
+    // WindowSeconds is the lookback window ending at evaluation time.
+    // +kubebuilder:validation:Minimum=1
+    WindowSeconds int32 `json:"windowSeconds"`
+
+    // StepSeconds is the query resolution. Defaults to window/60 (at least 1s).
+    // +optional
+    // +kubebuilder:validation:Minimum=1
+    StepSeconds *int32 `json:"stepSeconds,omitempty"`
+
+    // Reducer turns the window into one value. "slope" is the per-second
+    // rate of change from a least-squares fit, like PromQL deriv().
+    // +kubebuilder:validation:Enum=avg;max;min;p90;p95;last;slope
+    Reducer RangeReducer `json:"reducer"`
+}
+
+// SeriesReduce decides how a query returning several series becomes one value.
+type SeriesReduce string
+
+const (
+    SeriesReduceSum   SeriesReduce = "sum"
+    SeriesReduceMax   SeriesReduce = "max"
+    SeriesReduceMin   SeriesReduce = "min"
+    SeriesReduceAvg   SeriesReduce = "avg"
+    SeriesReduceCount SeriesReduce = "count"
+
+    // SeriesReduceError fails the metric when more than one series is left.
+    SeriesReduceError SeriesReduce = "error"
+)
+
 // MetricSpec describes one PromQL-based signal used to drive scaling.
+// +kubebuilder:validation:XValidation:rule="!has(self.role) || self.role != 'gate' || has(self.gate)",message="gate is required when role is gate"
+// +kubebuilder:validation:XValidation:rule="!has(self.maxSampleAgeSeconds) || has(self.range)",message="maxSampleAgeSeconds requires range"
 type MetricSpec struct {
     // Name is a logical name for this metric within the policy.
     Name string `json:"name"`
 
     // PromQL is the query we send to Prometheus.
     // Ideally it evaluates to a single scalar or a single-element vector.
+    // It is rendered as a Go template first; available fields are
+    // .Namespace, .TargetName, .TargetKind, .CurrentReplicas, .Labels (the
+    // target's labels) and .Variables (spec.variables). Use the quote
+    // function to insert values as escaped string literals, e.g.
+    // sum(rate(http_requests_total{app={{ quote .Labels.app }}}[2m])).
     PromQL string `json:"promQL"`
 
+    // SeriesSelector keeps only the series whose labels equal all of these
+    // values before SeriesReduce is applied.
+    // +optional
+    SeriesSelector map[string]string `json:"seriesSelector,omitempty"`
+
+    // SeriesReduce combines the remaining series into one value. For range
+    // queries it is applied per timestamp, before the range reducer.
+    // Defaults to error, so a query that unexpectedly returns several series
+    // fails loudly instead of picking an arbitrary one.
+    // +kubebuilder:validation:Enum=sum;max;min;avg;count;error
+    // +optional
+    SeriesReduce SeriesReduce `json:"seriesReduce,omitempty"`
+
+    // Range optionally evaluates PromQL as a range query over a window and
+    // reduces the result instead of running an instant query.
+    // +optional
+    Range *RangeQuerySpec `json:"range,omitempty"`
+
+    // Role decides whether this metric votes on replicas (scale) or acts
+    // as a safety gate that can veto scaling (gate). Defaults to scale.
+    // +kubebuilder:validation:Enum=scale;gate
+    // +optional
+    Role MetricRole `json:"role,omitempty"`
+
+    // Gate configures the veto; required when Role is gate.
+    // +optional
+    Gate *GateSpec `json:"gate,omitempty"`
+
     // Weight is used when aggregation=weighted to combine decisions.
     // +optional
     // +kubebuilder:validation:Minimum=0
@@ -69,6 +422,37 @@ type MetricSpec struct {
     // ScaleDown defines how we behave when the metric is below a threshold.
     // +optional
     ScaleDown *ScaleDirection `json:"scaleDown,omitempty"`
+
+    // Target switches this metric to proportional scaling. When set,
+    // ScaleUp and ScaleDown are ignored for this metric.
+    // +optional
+    Target *MetricTarget `json:"target,omitempty"`
+
+    // OnError decides how this metric contributes when its query fails.
+    // By default a failing metric is treated as neutral.
+    // +optional
+    OnError *MetricErrorPolicy `json:"onError,omitempty"`
+
+    // MaxSampleAgeSeconds rejects the window when its newest point is older
+    // than this. Requires Range: Prometheus stamps instant query results with
+    // the evaluation time, so their real age is unknown.
+    // Rejected samples are handled by OnError.
+    // +optional
+    // +kubebuilder:validation:Minimum=1
+    MaxSampleAgeSeconds *int32 `json:"maxSampleAgeSeconds,omitempty"`
+
+    // OnNonFinite decides what to do with NaN and ±Inf samples.
+    // Defaults to Missing.
+    // +optional
+    OnNonFinite *NonFinitePolicy `json:"onNonFinite,omitempty"`
+
+    // FailOnPartialResponse rejects samples that came with a partial response
+    // warning, e.g. Thanos or Mimir answering while some stores or ingesters
+    // were unavailable. Rejected samples are handled by OnError. PromQL
+    // annotations ("PromQL info: ...", "PromQL warning: ...") never count as
+    // partial responses.
+    // +optional
+    FailOnPartialResponse bool `json:"failOnPartialResponse,omitempty"`
 }
 
 // AggregationStrategy defines how we combine per-metric desired replicas.
@@ -110,12 +494,77 @@ type BehaviorSpec struct {
     MaxScaleDownStepPercent *int32 `json:"maxScaleDownStepPercent,omitempty"`
 }
 
-// TargetRef points to the workload we want to scale.
+// FallbackBehavior decides how fallback replicas relate to the current count.
+type FallbackBehavior string
+
+const (
+    // FallbackStatic sets exactly the fallback replica count.
+    FallbackStatic FallbackBehavior = "Static"
+
+    // FallbackAtLeast sets max(current, replicas) and never scales down.
+    FallbackAtLeast FallbackBehavior = "AtLeast"
+)
+
+// FallbackSpec defines failsafe replicas used while Prometheus is unavailable.
+type FallbackSpec struct {
+    // FailureThreshold is the number of consecutive evaluations in which
+    // Prometheus could not answer (every query failed with a 5xx, timeout,
+    // connection error or open circuit) after which fallback engages.
+    // +kubebuilder:validation:Minimum=1
+    FailureThreshold int32 `json:"failureThreshold"`
+
+    // Replicas is the fallback replica count (still bounded by min/max).
+    // +kubebuilder:validation:Minimum=1
+    Replicas int32 `json:"replicas"`
+
+    // Behavior defaults to Static.
+    // +kubebuilder:validation:Enum=Static;AtLeast
+    // +optional
+    Behavior FallbackBehavior `json:"behavior,omitempty"`
+}
+
+// OverrideSpec pins the target to a fixed replica count until a deadline.
+type OverrideSpec struct {
+    // Replicas is applied as is, ignoring minReplicas and maxReplicas.
+    // +kubebuilder:validation:Minimum=0
+    Replicas int32 `json:"replicas"`
+
+    // Until is when the override expires and the policy engine takes over
+    // again. Expired overrides are ignored and can be removed at leisure.
+    Until metav1.Time `json:"until"`
+
+    // Reason is shown in status and events, e.g. who set the override and why.
+    // +optional
+    Reason string `json:"reason,omitempty"`
+}
+
+// OverrideStatus describes the active suspend, pause or override.
+type OverrideStatus struct {
+    Source OverrideSource `json:"source"`
+
+    // Replicas is the pinned replica count; empty while suspended or paused.
+    // +optional
+    Replicas *int32 `json:"replicas,omitempty"`
+
+    // Until is when the override ends; empty while suspended or paused.
+    // +optional
+    Until *metav1.Time `json:"until,omitempty"`
+
+    // +optional
+    Reason string `json:"reason,omitempty"`
+}
+
+// TargetRef points to the workload we want to scale. Any kind that
+// implements the /scale subresource works (Deployment, StatefulSet,
+// ReplicaSet, Argo Rollout, custom resources, ...).
 type TargetRef struct {
     APIVersion string `json:"apiVersion"`
     Kind       string `json:"kind"`
     Name       string `json:"name"`
-    Namespace  string `json:"namespace"`
+
+    // Namespace defaults to the autoscaler's namespace when empty.
+    // +optional
+    Namespace string `json:"namespace,omitempty"`
 }
 
 // PrometheusAutoscalerSpec defines the desired behavior for one autoscaler.
@@ -136,6 +585,13 @@ type PrometheusAutoscalerSpec struct {
     // +optional
     Mode Mode `json:"mode,omitempty"`
 
+    // EvaluationIntervalSeconds is how often metrics are queried and a
+    // decision is made. Defaults to the controller's
+    // --default-evaluation-interval (30s unless overridden).
+    // +optional
+    // +kubebuilder:validation:Minimum=1
+    EvaluationIntervalSeconds *int32 `json:"evaluationIntervalSeconds,omitempty"`
+
     // Prometheus configuration for this autoscaler.
     Prometheus PrometheusConfig `json:"prometheus"`
 
@@ -144,20 +600,106 @@ type PrometheusAutoscalerSpec struct {
     // +optional
     Aggregation AggregationStrategy `json:"aggregation,omitempty"`
 
+    // Variables are user-defined values available to PromQL templates as
+    // {{ .Variables.<name> }}.
+    // +optional
+    Variables map[string]string `json:"variables,omitempty"`
+
     // Metrics is the list of PromQL-based signals that drive scaling.
     Metrics []MetricSpec `json:"metrics"`
 
+    // MaxConcurrentQueries bounds how many metric queries of this
+    // autoscaler run in parallel. Defaults to 4; 1 queries sequentially.
+    // +optional
+    // +kubebuilder:validation:Minimum=1
+    MaxConcurrentQueries *int32 `json:"maxConcurrentQueries,omitempty"`
+
+    // MinHealthyMetrics is the minimum number of metrics that must be queried
+    // successfully for the controller to act; otherwise replicas are held.
+    // By default there is no minimum.
+    // +optional
+    // +kubebuilder:validation:Minimum=0
+    MinHealthyMetrics *int32 `json:"minHealthyMetrics,omitempty"`
+
     // Behavior defines stabilization, cooldown and rate limiting knobs.
     // +optional
     Behavior *BehaviorSpec `json:"behavior,omitempty"`
+
+    // Fallback sets failsafe replicas after repeated failed evaluations,
+    // e.g. while Prometheus is down.
+    // +optional
+    Fallback *FallbackSpec `json:"fallback,omitempty"`
+
+    // Suspend stops evaluation and scaling; the target keeps its current
+    // replicas until the autoscaler is resumed. See also PausedAnnotation.
+    // +optional
+    Suspend bool `json:"suspend,omitempty"`
+
+    // Override pins the target's replicas until override.until, bypassing
+    // metrics and policy. Suspend takes precedence.
+    // +optional
+    Override *OverrideSpec `json:"override,omitempty"`
+
+    // OnDelete decides what happens to the target's replicas when this
+    // autoscaler is deleted. Nothing is scaled in DryRun mode or while
+    // another autoscaler controls the target. Defaults to Retain.
+    // +kubebuilder:validation:Enum=Retain;Restore;MinReplicas
+    // +optional
+    OnDelete OnDeletePolicy `json:"onDelete,omitempty"`
+}
+
+// MetricStatus records the last query outcome of one metric.
+type MetricStatus struct {
+    // Name matches MetricSpec.Name.
+    Name string `json:"name"`
+
+    // Query is the PromQL sent in the last evaluation, after templating.
+    // +optional
+    Query string `json:"query,omitempty"`
+
+    // Value is the last successfully queried sample.
+    // +optional
+    Value *float64 `json:"value,omitempty"`
+
+    // LastSuccessTime is when Value was queried.
+    // +optional
+    LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+
+    // SeriesCount is how many series the last query returned, before
+    // SeriesSelector was applied.
+    // +optional
+    SeriesCount int32 `json:"seriesCount,omitempty"`
+
+    // Endpoint is the Prometheus URL that answered the last query.
+    // +optional
+    Endpoint string `json:"endpoint,omitempty"`
+
+    // Warnings are the warnings Prometheus returned with the last query.
+    // +optional
+    Warnings []string `json:"warnings,omitempty"`
+
+    // Error is the last query error; empty when the last query succeeded.
+    // +optional
+    Error string `json:"error,omitempty"`
 }
 
 // PrometheusAutoscalerStatus captures what the controller last computed/applied.
 type PrometheusAutoscalerStatus struct {
-    // CurrentReplicas is what we see on the target workload right now.
+    // CurrentReplicas is what we see on the target workload right now,
+    // as reported by its scale subresource.
     // +optional
     CurrentReplicas *int32 `json:"currentReplicas,omitempty"`
 
+    // Selector is the label selector of the target's pods, taken from its
+    // scale subresource.
+    // +optional
+    Selector string `json:"selector,omitempty"`
+
+    // OriginalReplicas is the target's replica count when this autoscaler
+    // first took control of it. onDelete=Restore scales back to it.
+    // +optional
+    OriginalReplicas *int32 `json:"originalReplicas,omitempty"`
+
     // DesiredReplicas is what the policy engine last computed.
     // +optional
     DesiredReplicas *int32 `json:"desiredReplicas,omitempty"`
@@ -171,6 +713,34 @@ type PrometheusAutoscalerStatus struct {
     // +optional
     LastPrometheusSample string `json:"lastPrometheusSample,omitempty"`
 
+    // Metrics holds the per-metric outcome of the last evaluation, including
+    // the last known values used by onError=UseLastKnown.
+    // +optional
+    Metrics []MetricStatus `json:"metrics,omitempty"`
+
+    // FailingMetrics lists the metrics whose query failed in the last evaluation.
+    // +optional
+    FailingMetrics []string `json:"failingMetrics,omitempty"`
+
+    // ServedBy lists the Prometheus URLs that answered the queries of the
+    // last decision.
+    // +optional
+    ServedBy []string `json:"servedBy,omitempty"`
+
+    // Override is the active suspend, pause or override, if any.
+    // +optional
+    Override *OverrideStatus `json:"override,omitempty"`
+
+    // ConsecutiveFailures counts evaluations in a row that could not query
+    // Prometheus at all. It drives spec.fallback.
+    // +optional
+    ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+    // LastFailureTime is when ConsecutiveFailures was last incremented; at
+    // most one failure is counted per evaluation interval.
+    // +optional
+    LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+
     // Conditions follows the standard Kubernetes pattern to surface health.
     // +optional
     Conditions []metav1.Condition `json:"conditions,omitempty"`
@@ -0,0 +1,107 @@
+package v1alpha1
+
+import (
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PrometheusEndpointSpec is the connection shared by every autoscaler that
+// references the endpoint.
+// +kubebuilder:validation:XValidation:rule="has(self.url)",message="url is required"
+type PrometheusEndpointSpec struct {
+    PrometheusConnection `json:",inline"`
+}
+
+// PrometheusBuildInfo is what the endpoint reports on /api/v1/status/buildinfo.
+type PrometheusBuildInfo struct {
+    // +optional
+    Version string `json:"version,omitempty"`
+
+    // +optional
+    Revision string `json:"revision,omitempty"`
+
+    // +optional
+    Branch string `json:"branch,omitempty"`
+
+    // +optional
+    GoVersion string `json:"goVersion,omitempty"`
+}
+
+// PrometheusEndpointStatus reports the result of the last probe.
+type PrometheusEndpointStatus struct {
+    // ObservedGeneration is the spec generation the status refers to.
+    // +optional
+    ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+    // LastProbeTime is when the endpoint was last probed.
+    // +optional
+    LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+
+    // BuildInfo is the last build information reported by the endpoint.
+    // Backends without the buildinfo API leave it empty.
+    // +optional
+    BuildInfo *PrometheusBuildInfo `json:"buildInfo,omitempty"`
+
+    // Conditions carries Reachable.
+    // +optional
+    Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// PrometheusEndpoint is a Prometheus compatible API that autoscalers in the
+// same namespace can reference via spec.prometheus.endpointRef. Secrets and
+// ConfigMaps it references live in its own namespace.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=prometheusendpoints,scope=Namespaced,shortName=pe
+// +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.url`
+// +kubebuilder:printcolumn:name="Reachable",type=string,JSONPath=`.status.conditions[?(@.type=="Reachable")].status`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.buildInfo.version`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type PrometheusEndpoint struct {
+    metav1.TypeMeta   `json:",inline"`
+    metav1.ObjectMeta `json:"metadata,omitempty"`
+
+    Spec   PrometheusEndpointSpec   `json:"spec,omitempty"`
+    Status PrometheusEndpointStatus `json:"status,omitempty"`
+}
+
+// PrometheusEndpointList contains a list of PrometheusEndpoint.
+// +kubebuilder:object:root=true
+type PrometheusEndpointList struct {
+    metav1.TypeMeta `json:",inline"`
+    metav1.ListMeta `json:"metadata,omitempty"`
+    Items           []PrometheusEndpoint `json:"items"`
+}
+
+// ClusterPrometheusEndpoint is a cluster-wide PrometheusEndpoint usable by
+// autoscalers in every namespace. Secrets and ConfigMaps it references are
+// read from the controller's cluster resource namespace
+// (--cluster-resource-namespace).
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=clusterprometheusendpoints,scope=Cluster,shortName=cpe
+// +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.url`
+// +kubebuilder:printcolumn:name="Reachable",type=string,JSONPath=`.status.conditions[?(@.type=="Reachable")].status`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.buildInfo.version`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type ClusterPrometheusEndpoint struct {
+    metav1.TypeMeta   `json:",inline"`
+    metav1.ObjectMeta `json:"metadata,omitempty"`
+
+    Spec   PrometheusEndpointSpec   `json:"spec,omitempty"`
+    Status PrometheusEndpointStatus `json:"status,omitempty"`
+}
+
+// ClusterPrometheusEndpointList contains a list of ClusterPrometheusEndpoint.
+// +kubebuilder:object:root=true
+type ClusterPrometheusEndpointList struct {
+    metav1.TypeMeta `json:",inline"`
+    metav1.ListMeta `json:"metadata,omitempty"`
+    Items           []ClusterPrometheusEndpoint `json:"items"`
+}
+
+func init() {
+    SchemeBuilder.Register(
+        &PrometheusEndpoint{}, &PrometheusEndpointList{},
+        &ClusterPrometheusEndpoint{}, &ClusterPrometheusEndpointList{},
+    )
+}
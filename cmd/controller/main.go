@@ -6,10 +6,7 @@ import (
 
 "github.com/go-logr/zapr"
 autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
-autoscalercontroller "github.com/MreliotA/prometheus-policy-autoscaler/pkg/controller"
-"github.com/MreliotA/prometheus-policy-autoscaler/pkg/history"
-"github.com/MreliotA/prometheus-policy-autoscaler/pkg/metrics"
-"github.com/MreliotA/prometheus-policy-autoscaler/pkg/policy"
+"github.com/MreliotA/prometheus-policy-autoscaler/pkg/manager"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -17,6 +14,7 @@ autoscalercontroller "github.com/MreliotA/prometheus-policy-autoscaler/pkg/contr
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
 var (
@@ -44,6 +42,10 @@ func main() {
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "Address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", true, "Enable leader election for controller manager.")
 	flag.StringVar(&logLevel, "log-level", "info", "Log level: debug|info|warn|error")
+
+	// Controller settings are shared with the other entry point.
+	opts := manager.Options{RecorderName: "prometheus-policy-autoscaler"}
+	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
 	// Configure a structured JSON logger for production use.
@@ -74,7 +76,7 @@ func main() {
 
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
+		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "prometheus-policy-autoscaler.parspack.dev",
@@ -84,26 +86,8 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Shared dependencies for the reconciler.
-	historyStore := history.NewStore()
-	engine := policy.NewEngine()
-
-	reconciler := &autoscalercontroller.PrometheusAutoscalerReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("prometheus-policy-autoscaler"),
-		Logger:   ctrl.Log.WithName("controller").WithName("PrometheusAutoscaler"),
-
-		PromClientFactory: func(url string) (metrics.Client, error) {
-			// This factory keeps the reconciler decoupled from concrete implementations.
-			return metrics.NewHTTPClient(url)
-		},
-		PolicyEngine: engine,
-		HistoryStore: historyStore,
-	}
-
-	if err := reconciler.SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PrometheusAutoscaler")
+	if err := manager.SetupControllers(mgr, opts); err != nil {
+		setupLog.Error(err, "unable to set up controllers")
 		os.Exit(1)
 	}
 
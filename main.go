@@ -6,10 +6,7 @@ import (
 
     "github.com/go-logr/zapr"
     autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
-    "github.com/MreliotA/prometheus-policy-autoscaler/controllers"
-    "github.com/MreliotA/prometheus-policy-autoscaler/pkg/history"
-    "github.com/MreliotA/prometheus-policy-autoscaler/pkg/metrics"
-    "github.com/MreliotA/prometheus-policy-autoscaler/pkg/policy"
+    "github.com/MreliotA/prometheus-policy-autoscaler/pkg/manager"
     "go.uber.org/zap"
     "go.uber.org/zap/zapcore"
     "k8s.io/apimachinery/pkg/runtime"
@@ -17,6 +14,7 @@ import (
     clientgoscheme "k8s.io/client-go/kubernetes/scheme"
     ctrl "sigs.k8s.io/controller-runtime"
     "sigs.k8s.io/controller-runtime/pkg/healthz"
+    metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
 var (
@@ -45,6 +43,10 @@ func main() {
     flag.BoolVar(&enableLeaderElection, "leader-elect", false,
         "Enable leader election for controller manager. Ensures only one active instance.")
     flag.StringVar(&logLevel, "log-level", "info", "Log level: debug|info|warn|error")
+
+    // Controller settings are shared with the other entry point.
+    opts := manager.Options{RecorderName: "prometheus-autoscaler"}
+    opts.BindFlags(flag.CommandLine)
     flag.Parse()
 
     // Configure a structured JSON logger. This is production-friendly and plays
@@ -75,7 +77,7 @@ func main() {
 
     mgr, err := ctrl.NewManager(cfg, ctrl.Options{
         Scheme:                 scheme,
-        MetricsBindAddress:     metricsAddr,
+        Metrics:                metricsserver.Options{BindAddress: metricsAddr},
         HealthProbeBindAddress: probeAddr,
         LeaderElection:         enableLeaderElection,
         LeaderElectionID:       "prometheus-autoscaler.parspack.dev",
@@ -85,27 +87,8 @@ func main() {
         os.Exit(1)
     }
 
-    // Shared dependencies for the reconciler.
-    historyStore := history.NewStore()
-    engine := policy.NewEngine()
-
-    reconciler := &controllers.PrometheusAutoscalerReconciler{
-        Client: mgr.GetClient(),
-        Scheme: mgr.GetScheme(),
-        Recorder: mgr.GetEventRecorderFor("prometheus-autoscaler"),
-        Logger:   ctrl.Log.WithName("controller").WithName("PrometheusAutoscaler"),
-
-        PromClientFactory: func(url string) (metrics.Client, error) {
-            // In a real setup, you might want to cache these clients per URL
-            // instead of creating new ones on each reconciliation.
-            return metrics.NewHTTPClient(url)
-        },
-        PolicyEngine: engine,
-        HistoryStore: historyStore,
-    }
-
-    if err := reconciler.SetupWithManager(mgr); err != nil {
-        setupLog.Error(err, "unable to create controller", "controller", "PrometheusAutoscaler")
+    if err := manager.SetupControllers(mgr, opts); err != nil {
+        setupLog.Error(err, "unable to set up controllers")
         os.Exit(1)
     }
 
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// kedaGroup owns the ScaledObjects whose HPAs we report by their
+// ScaledObject rather than by the generated HPA name.
+const kedaGroup = "keda.sh"
+
+// findConflict returns a description of another autoscaler that drives the
+// same target, or "" when this autoscaler may scale it. HPAs (including the
+// ones KEDA creates for ScaledObjects) always win because they would fight
+// us on every sync. Among PrometheusAutoscalers the oldest one wins.
+func (r *PrometheusAutoscalerReconciler) findConflict(
+	ctx context.Context,
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+	target *scaleTarget,
+) (string, error) {
+	var hpas autoscalingv2.HorizontalPodAutoscalerList
+	if err := r.List(ctx, &hpas, client.InNamespace(target.Key.Namespace)); err != nil {
+		return "", fmt.Errorf("listing HorizontalPodAutoscalers: %w", err)
+	}
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		if !refersTo(hpa.Spec.ScaleTargetRef, target) {
+			continue
+		}
+		if owner := metav1.GetControllerOf(hpa); owner != nil && owner.Kind == "ScaledObject" {
+			if gv, err := schema.ParseGroupVersion(owner.APIVersion); err == nil && gv.Group == kedaGroup {
+				return fmt.Sprintf("KEDA ScaledObject %s/%s (HorizontalPodAutoscaler %s)",
+					hpa.Namespace, owner.Name, hpa.Name), nil
+			}
+		}
+		return fmt.Sprintf("HorizontalPodAutoscaler %s/%s", hpa.Namespace, hpa.Name), nil
+	}
+
+	var list autoscalerv1alpha1.PrometheusAutoscalerList
+	if err := r.List(ctx, &list, client.MatchingFields{
+		targetRefIndexKey: targetIndexValue(target.GVK.GroupKind(), target.Key.Namespace, target.Key.Name),
+	}); err != nil {
+		return "", fmt.Errorf("listing PrometheusAutoscalers: %w", err)
+	}
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.UID == pa.UID || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if olderThan(other, pa) {
+			return fmt.Sprintf("PrometheusAutoscaler %s/%s", other.Namespace, other.Name), nil
+		}
+	}
+	return "", nil
+}
+
+// autoscalersForHPA maps an HPA event to the autoscalers of its target.
+func (r *PrometheusAutoscalerReconciler) autoscalersForHPA(ctx context.Context, obj client.Object) []reconcile.Request {
+	hpa, ok := obj.(*autoscalingv2.HorizontalPodAutoscaler)
+	if !ok {
+		return nil
+	}
+	gv, err := schema.ParseGroupVersion(hpa.Spec.ScaleTargetRef.APIVersion)
+	if err != nil {
+		return nil
+	}
+	gk := gv.WithKind(hpa.Spec.ScaleTargetRef.Kind).GroupKind()
+	target := &metav1.PartialObjectMetadata{}
+	target.SetNamespace(hpa.Namespace)
+	target.SetName(hpa.Spec.ScaleTargetRef.Name)
+	return r.autoscalersForTarget(gk)(ctx, target)
+}
+
+// refersTo reports whether an HPA scale target reference points at target.
+func refersTo(ref autoscalingv2.CrossVersionObjectReference, target *scaleTarget) bool {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false
+	}
+	return gv.Group == target.GVK.Group && ref.Kind == target.GVK.Kind && ref.Name == target.Key.Name
+}
+
+// olderThan orders autoscalers by creation time, breaking ties by
+// namespace/name so exactly one of two conflicting autoscalers wins.
+func olderThan(a, b *autoscalerv1alpha1.PrometheusAutoscaler) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return client.ObjectKeyFromObject(a).String() < client.ObjectKeyFromObject(b).String()
+}
+
+// reportConflict sets AbleToScale=False and emits a warning event when the
+// conflict is new or changed.
+func (r *PrometheusAutoscalerReconciler) reportConflict(pa *autoscalerv1alpha1.PrometheusAutoscaler, conflict string) {
+	msg := "Target is also scaled by " + conflict
+	if c := meta.FindStatusCondition(pa.Status.Conditions, "AbleToScale"); c == nil ||
+		c.Status != metav1.ConditionFalse || c.Message != msg {
+		r.Recorder.Eventf(pa, "Warning", "ConflictingAutoscaler",
+			"Not scaling: target is also scaled by %s", conflict)
+	}
+	r.setCondition(pa, "AbleToScale", metav1.ConditionFalse, "ConflictingAutoscaler", "%s", msg)
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	"github.com/MreliotA/prometheus-policy-autoscaler/pkg/telemetry"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// recordEvaluationFailure counts a failed evaluation and engages fallback
+// once spec.fallback.failureThreshold is reached. It returns true when
+// fallback is active and the normal decision must be skipped.
+func (r *PrometheusAutoscalerReconciler) recordEvaluationFailure(
+	ctx context.Context,
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+	target *scaleTarget,
+	now time.Time,
+) (bool, error) {
+	// Extra reconciles (spec edits, watched objects changing) must not count
+	// as separate failures: at most one is counted per evaluation interval.
+	if last := pa.Status.LastFailureTime; last == nil || now.Sub(last.Time) >= r.evaluationInterval(pa) {
+		pa.Status.ConsecutiveFailures++
+		pa.Status.LastFailureTime = &metav1.Time{Time: now}
+	}
+
+	fb := pa.Spec.Fallback
+	if fb == nil || pa.Status.ConsecutiveFailures < fb.FailureThreshold {
+		return false, nil
+	}
+
+	current := target.Scale.Spec.Replicas
+	replicas := fallbackReplicas(fb, current, pa.Spec.MinReplicas, pa.Spec.MaxReplicas)
+	pa.Status.DesiredReplicas = &replicas
+
+	if !meta.IsStatusConditionTrue(pa.Status.Conditions, "Fallback") {
+		r.Recorder.Eventf(pa, "Warning", "FallbackEngaged",
+			"Fallback engaged after %d consecutive failed evaluations; using %d replicas",
+			pa.Status.ConsecutiveFailures, replicas)
+	}
+	r.setCondition(pa, "Fallback", metav1.ConditionTrue, "FallbackActive",
+		"%d consecutive failed evaluations; using fallback of %d replicas",
+		pa.Status.ConsecutiveFailures, replicas)
+
+	if pa.Spec.Mode == autoscalerv1alpha1.ModeDryRun || replicas == current {
+		return true, nil
+	}
+
+	if err := r.updateScale(ctx, target, replicas); err != nil {
+		return true, fmt.Errorf("applying fallback replicas to %s %s: %w", target.GVK.Kind, target.Key, err)
+	}
+	pa.Status.LastScaleTime = &metav1.Time{Time: now}
+	telemetry.RecordScale(pa.Namespace, pa.Name, current, replicas)
+	r.Recorder.Eventf(pa, "Normal", "Scaled",
+		"Scaled target %s %s from %d to %d (fallback)",
+		target.GVK.Kind, target.Key, current, replicas)
+
+	return true, nil
+}
+
+// recordEvaluationSuccess resets the failure counter and disengages fallback.
+func (r *PrometheusAutoscalerReconciler) recordEvaluationSuccess(pa *autoscalerv1alpha1.PrometheusAutoscaler) {
+	pa.Status.ConsecutiveFailures = 0
+	pa.Status.LastFailureTime = nil
+
+	if meta.IsStatusConditionTrue(pa.Status.Conditions, "Fallback") {
+		r.setCondition(pa, "Fallback", metav1.ConditionFalse, "MetricsRecovered",
+			"Prometheus evaluations succeed again")
+		r.Recorder.Event(pa, "Normal", "FallbackDisengaged",
+			"Fallback disengaged; metrics-based scaling resumed")
+	}
+}
+
+// fallbackReplicas computes the failsafe replica count within min/max.
+func fallbackReplicas(fb *autoscalerv1alpha1.FallbackSpec, current, minReplicas, maxReplicas int32) int32 {
+	replicas := fb.Replicas
+	if fb.Behavior == autoscalerv1alpha1.FallbackAtLeast && current > replicas {
+		replicas = current
+	}
+	if replicas < minReplicas {
+		replicas = minReplicas
+	}
+	if replicas > maxReplicas {
+		replicas = maxReplicas
+	}
+	return replicas
+}
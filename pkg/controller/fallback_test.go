@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"testing"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+)
+
+func TestFallbackReplicas(t *testing.T) {
+	tests := []struct {
+		name     string
+		behavior autoscalerv1alpha1.FallbackBehavior
+		replicas int32
+		current  int32
+		want     int32
+	}{
+		{"static scales down", autoscalerv1alpha1.FallbackStatic, 4, 8, 4},
+		{"static scales up", autoscalerv1alpha1.FallbackStatic, 4, 2, 4},
+		{"at least keeps more replicas", autoscalerv1alpha1.FallbackAtLeast, 4, 8, 8},
+		{"at least scales up", autoscalerv1alpha1.FallbackAtLeast, 4, 2, 4},
+		{"clamped to min", autoscalerv1alpha1.FallbackStatic, 0, 5, 2},
+		{"clamped to max", autoscalerv1alpha1.FallbackStatic, 50, 5, 10},
+		{"at least clamped to max", autoscalerv1alpha1.FallbackAtLeast, 4, 12, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := &autoscalerv1alpha1.FallbackSpec{Replicas: tt.replicas, Behavior: tt.behavior}
+			if got := fallbackReplicas(fb, tt.current, 2, 10); got != tt.want {
+				t.Errorf("fallbackReplicas = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// autoscalerFinalizer holds deletion until the target has been handled
+// according to spec.onDelete and the decision history is removed.
+const autoscalerFinalizer = "autoscaler.parspack.dev/finalizer"
+
+// finalize applies spec.onDelete to the target and deletes the autoscaler's
+// history. A target that no longer exists is not an error.
+func (r *PrometheusAutoscalerReconciler) finalize(ctx context.Context, pa *autoscalerv1alpha1.PrometheusAutoscaler) error {
+	log := r.Logger.WithValues("prometheusautoscaler", client.ObjectKeyFromObject(pa))
+
+	if replicas, ok := onDeleteReplicas(pa); ok {
+		switch {
+		case pa.Spec.Mode == autoscalerv1alpha1.ModeDryRun:
+			log.Info("dry-run mode: not applying onDelete", "onDelete", pa.Spec.OnDelete, "replicas", replicas)
+		case meta.IsStatusConditionFalse(pa.Status.Conditions, "AbleToScale"):
+			log.Info("not applying onDelete, another autoscaler controls the target", "onDelete", pa.Spec.OnDelete)
+		default:
+			if err := r.scaleOnDelete(ctx, pa, replicas); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := r.HistoryStore.Delete(ctx, client.ObjectKeyFromObject(pa).String()); err != nil {
+		return fmt.Errorf("deleting decision history: %w", err)
+	}
+	r.forgetAppliedReplicas(pa)
+	return nil
+}
+
+// scaleOnDelete sets the target to replicas unless it is already there or gone.
+func (r *PrometheusAutoscalerReconciler) scaleOnDelete(
+	ctx context.Context,
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+	replicas int32,
+) error {
+	target, err := r.getScaleTarget(ctx, pa)
+	if err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("getting scale of target %s %s: %w", pa.Spec.TargetRef.Kind, targetKey(pa), err)
+	}
+
+	current := target.Scale.Spec.Replicas
+	if current == replicas {
+		return nil
+	}
+	if err := r.updateScale(ctx, target, replicas); err != nil {
+		return fmt.Errorf("applying onDelete=%s to %s %s: %w", pa.Spec.OnDelete, target.GVK.Kind, target.Key, err)
+	}
+	r.Recorder.Eventf(pa, "Normal", "Scaled",
+		"Scaled target %s %s from %d to %d (onDelete=%s)",
+		target.GVK.Kind, target.Key, current, replicas, pa.Spec.OnDelete)
+	return nil
+}
+
+// onDeleteReplicas returns the replicas spec.onDelete asks for, if any.
+func onDeleteReplicas(pa *autoscalerv1alpha1.PrometheusAutoscaler) (int32, bool) {
+	switch pa.Spec.OnDelete {
+	case autoscalerv1alpha1.OnDeleteRestore:
+		if pa.Status.OriginalReplicas == nil {
+			return 0, false
+		}
+		return *pa.Status.OriginalReplicas, true
+	case autoscalerv1alpha1.OnDeleteMinReplicas:
+		return pa.Spec.MinReplicas, true
+	default:
+		return 0, false
+	}
+}
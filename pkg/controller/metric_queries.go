@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	"github.com/MreliotA/prometheus-policy-autoscaler/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultLastKnownMaxAge bounds onError=UseLastKnown when no age is configured.
+const defaultLastKnownMaxAge = 5 * time.Minute
+
+// metricResults is the outcome of querying every metric of an autoscaler.
+type metricResults struct {
+	// Samples feeds the policy engine, including substituted last known values.
+	Samples map[string]float64
+
+	// Failures maps failing metrics to their error message.
+	Failures map[string]string
+
+	// Failing lists failing metric names in spec order.
+	Failing []string
+
+	// ServedBy lists the distinct endpoints that answered, in spec order.
+	ServedBy []string
+
+	// Statuses is the new per-metric status, in spec order.
+	Statuses []autoscalerv1alpha1.MetricStatus
+
+	// Warnings lists "metric: warning" for every warning Prometheus returned,
+	// in spec order.
+	Warnings []string
+
+	// CircuitOpen holds the breaker error when a query was refused because
+	// the endpoint's circuit breaker is open.
+	CircuitOpen error
+
+	// EndpointFailure is set when at least one query failed because
+	// Prometheus could not answer, as opposed to a mistake in the spec
+	// (template, selection or sample validation errors).
+	EndpointFailure bool
+}
+
+// maxWarningsMessageLength caps the QueryWarnings condition message, well
+// below the API's 32768 character limit. The full list stays in
+// status.metrics[].warnings.
+const maxWarningsMessageLength = 1024
+
+// warningsMessage joins warnings for the QueryWarnings condition, dropping
+// the ones that don't fit into maxWarningsMessageLength.
+func warningsMessage(warnings []string) string {
+	var msg string
+	for i, w := range warnings {
+		next := w
+		if i > 0 {
+			next = msg + "; " + w
+		}
+		if len(next) <= maxWarningsMessageLength {
+			msg = next
+			continue
+		}
+		kept := i
+		if i == 0 {
+			msg = strings.ToValidUTF8(w[:maxWarningsMessageLength], "") + "…"
+			kept = 1
+		}
+		if rest := len(warnings) - kept; rest > 0 {
+			msg += fmt.Sprintf(" (and %d more, see status.metrics[].warnings)", rest)
+		}
+		return msg
+	}
+	return msg
+}
+
+// defaultMaxConcurrentQueries applies when spec.maxConcurrentQueries is unset.
+const defaultMaxConcurrentQueries = 4
+
+// metricOutcome is the result of evaluating one metric.
+type metricOutcome struct {
+	status autoscalerv1alpha1.MetricStatus
+
+	// sample is what the policy engine sees; nil when the metric has no
+	// usable value (not even a last known one).
+	sample *float64
+
+	// err is the query or validation error, if any.
+	err error
+}
+
+// queryMetrics runs every metric query, concurrently up to the autoscaler's
+// and the controller's limits. Results are collected in spec order so status
+// and decisions stay deterministic. A failing metric never aborts the
+// evaluation; it is recorded and handled by its onError policy instead.
+func (r *PrometheusAutoscalerReconciler) queryMetrics(
+	ctx context.Context,
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+	promClient metrics.Client,
+	qc queryContext,
+	now time.Time,
+) metricResults {
+	previous := make(map[string]autoscalerv1alpha1.MetricStatus, len(pa.Status.Metrics))
+	for _, ms := range pa.Status.Metrics {
+		previous[ms.Name] = ms
+	}
+
+	limit := defaultMaxConcurrentQueries
+	if pa.Spec.MaxConcurrentQueries != nil {
+		// Validation requires at least 1, but an unbuffered channel would
+		// block every query if an invalid value ever got through.
+		limit = max(1, int(*pa.Spec.MaxConcurrentQueries))
+	}
+	slots := make(chan struct{}, limit)
+
+	outcomes := make([]metricOutcome, len(pa.Spec.Metrics))
+	var wg sync.WaitGroup
+	for i, ms := range pa.Spec.Metrics {
+		wg.Add(1)
+		go func(i int, ms autoscalerv1alpha1.MetricSpec) {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			if r.querySlots != nil {
+				r.querySlots <- struct{}{}
+				defer func() { <-r.querySlots }()
+			}
+
+			outcomes[i] = r.evaluateMetric(ctx, pa, promClient, qc, ms, previous[ms.Name], now)
+		}(i, ms)
+	}
+	wg.Wait()
+
+	res := metricResults{
+		Samples:  make(map[string]float64),
+		Failures: make(map[string]string),
+	}
+	for i, ms := range pa.Spec.Metrics {
+		out := outcomes[i]
+		res.Statuses = append(res.Statuses, out.status)
+		for _, w := range out.status.Warnings {
+			res.Warnings = append(res.Warnings, ms.Name+": "+w)
+		}
+		if out.sample != nil {
+			res.Samples[ms.Name] = *out.sample
+		}
+		if out.err == nil {
+			if ep := out.status.Endpoint; ep != "" && !slices.Contains(res.ServedBy, ep) {
+				res.ServedBy = append(res.ServedBy, ep)
+			}
+			continue
+		}
+		res.Failures[ms.Name] = out.err.Error()
+		res.Failing = append(res.Failing, ms.Name)
+		if errors.Is(out.err, metrics.ErrCircuitOpen) {
+			res.CircuitOpen = out.err
+		}
+		if metrics.IsEndpointFailure(out.err) {
+			res.EndpointFailure = true
+		}
+	}
+
+	return res
+}
+
+// evaluateMetric renders, queries and validates a single metric.
+func (r *PrometheusAutoscalerReconciler) evaluateMetric(
+	ctx context.Context,
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+	promClient metrics.Client,
+	qc queryContext,
+	ms autoscalerv1alpha1.MetricSpec,
+	prev autoscalerv1alpha1.MetricStatus,
+	now time.Time,
+) metricOutcome {
+	log := r.Logger.WithValues("prometheusautoscaler", pa.Namespace+"/"+pa.Name)
+	status := autoscalerv1alpha1.MetricStatus{Name: ms.Name}
+
+	var result metrics.Result
+	promql, err := renderPromQL(ms, qc)
+	if err == nil {
+		status.Query = promql
+		result, err = queryMetric(ctx, promClient, ms, promql)
+		status.SeriesCount = int32(result.SeriesCount)
+		status.Endpoint = result.Endpoint
+		status.Warnings = result.Warnings
+		if len(result.Warnings) > 0 {
+			log.Info("Prometheus returned warnings", "metric", ms.Name, "warnings", result.Warnings)
+		}
+	}
+	if err == nil {
+		var val float64
+		if val, err = acceptSample(ms, result, now); err == nil {
+			status.Value = &val
+			status.LastSuccessTime = &metav1.Time{Time: now}
+			return metricOutcome{status: status, sample: &val}
+		}
+		log.Info("rejected Prometheus sample", "metric", ms.Name, "reason", err.Error())
+		r.Recorder.Eventf(pa, "Warning", "SampleRejected", "Metric %s: %v", ms.Name, err)
+	} else if !errors.Is(err, metrics.ErrCircuitOpen) {
+		log.Error(err, "failed to query Prometheus", "metric", ms.Name, "promql", promql)
+	}
+
+	// Keep the last known value around so UseLastKnown keeps working
+	// across several failed evaluations.
+	status.Value = prev.Value
+	status.LastSuccessTime = prev.LastSuccessTime
+	status.Error = err.Error()
+	out := metricOutcome{status: status, err: err}
+
+	if v, ok := lastKnownValue(ms, prev, now); ok {
+		log.Info("using last known value for failing metric", "metric", ms.Name, "value", v)
+		out.sample = &v
+	}
+	return out
+}
+
+// queryMetric runs the rendered promql as an instant query, or as a reduced
+// range query when the metric has a range configured.
+func queryMetric(
+	ctx context.Context,
+	promClient metrics.Client,
+	ms autoscalerv1alpha1.MetricSpec,
+	promql string,
+) (metrics.Result, error) {
+	sel := metrics.Selection{
+		Match:  ms.SeriesSelector,
+		Reduce: metrics.SeriesReduce(ms.SeriesReduce),
+	}
+	if ms.Range == nil {
+		return promClient.QueryVector(ctx, promql, sel)
+	}
+
+	rng := metrics.Range{
+		Window:  time.Duration(ms.Range.WindowSeconds) * time.Second,
+		Reducer: metrics.Reducer(ms.Range.Reducer),
+	}
+	if ms.Range.StepSeconds != nil {
+		rng.Step = time.Duration(*ms.Range.StepSeconds) * time.Second
+	}
+	return promClient.QueryRange(ctx, promql, rng, sel)
+}
+
+// acceptSample checks a query result for partial responses, staleness and
+// non-finite values and returns the value the policy engine should see.
+func acceptSample(ms autoscalerv1alpha1.MetricSpec, result metrics.Result, now time.Time) (float64, error) {
+	if ms.FailOnPartialResponse {
+		if partial := result.PartialResponse(); len(partial) > 0 {
+			return 0, fmt.Errorf("partial response: %s", strings.Join(partial, "; "))
+		}
+	}
+
+	// Only range results carry the time of the newest point; instant results
+	// are stamped with the evaluation time. Validation rejects the setting
+	// without range, this guards autoscalers created before that rule.
+	if ms.MaxSampleAgeSeconds != nil && ms.Range != nil && !result.Timestamp.IsZero() {
+		maxAge := time.Duration(*ms.MaxSampleAgeSeconds) * time.Second
+		if age := now.Sub(result.Timestamp); age > maxAge {
+			return 0, fmt.Errorf("sample is %s old, more than maxSampleAgeSeconds=%d",
+				age.Truncate(time.Second), *ms.MaxSampleAgeSeconds)
+		}
+	}
+
+	if !math.IsNaN(result.Value) && !math.IsInf(result.Value, 0) {
+		return result.Value, nil
+	}
+	if p := ms.OnNonFinite; p != nil && p.Action == autoscalerv1alpha1.NonFiniteReplace {
+		if p.Value == nil {
+			return 0, fmt.Errorf("sample is %v and onNonFinite.value is not set", result.Value)
+		}
+		return *p.Value, nil
+	}
+	return 0, fmt.Errorf("sample is %v", result.Value)
+}
+
+// lastKnownValue returns the previous sample if the metric asks for
+// onError=UseLastKnown and the value is still young enough.
+func lastKnownValue(
+	ms autoscalerv1alpha1.MetricSpec,
+	prev autoscalerv1alpha1.MetricStatus,
+	now time.Time,
+) (float64, bool) {
+	if ms.OnError == nil || ms.OnError.Action != autoscalerv1alpha1.MetricErrorUseLastKnown {
+		return 0, false
+	}
+	if prev.Value == nil || prev.LastSuccessTime == nil {
+		return 0, false
+	}
+
+	maxAge := defaultLastKnownMaxAge
+	if ms.OnError.LastKnownMaxAgeSeconds != nil {
+		maxAge = time.Duration(*ms.OnError.LastKnownMaxAgeSeconds) * time.Second
+	}
+	if now.Sub(prev.LastSuccessTime.Time) > maxAge {
+		return 0, false
+	}
+	return *prev.Value, true
+}
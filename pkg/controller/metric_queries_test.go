@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWarningsMessage(t *testing.T) {
+	long := strings.Repeat("x", maxWarningsMessageLength-10)
+
+	tests := []struct {
+		name     string
+		warnings []string
+		want     string
+	}{
+		{"none", nil, ""},
+		{"joined", []string{"a", "b"}, "a; b"},
+		{
+			"drops what doesn't fit",
+			[]string{long, "second warning", "third"},
+			long + " (and 2 more, see status.metrics[].warnings)",
+		},
+		{
+			"truncates a single long warning",
+			[]string{long + strings.Repeat("y", 20)},
+			long + strings.Repeat("y", 10) + "…",
+		},
+		{
+			"truncates the first of several",
+			[]string{long + strings.Repeat("y", 20), "b"},
+			long + strings.Repeat("y", 10) + "… (and 1 more, see status.metrics[].warnings)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := warningsMessage(tt.warnings); got != tt.want {
+				t.Errorf("warningsMessage = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	"github.com/MreliotA/prometheus-policy-autoscaler/pkg/telemetry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// activeOverride returns what currently takes precedence over the policy
+// engine: spec.suspend, the pause annotation or an unexpired spec.override.
+func activeOverride(pa *autoscalerv1alpha1.PrometheusAutoscaler, now time.Time) *autoscalerv1alpha1.OverrideStatus {
+	switch {
+	case pa.Spec.Suspend:
+		return &autoscalerv1alpha1.OverrideStatus{
+			Source: autoscalerv1alpha1.OverrideSourceSuspend,
+			Reason: "spec.suspend is true",
+		}
+	case pa.Annotations[autoscalerv1alpha1.PausedAnnotation] == "true":
+		return &autoscalerv1alpha1.OverrideStatus{
+			Source: autoscalerv1alpha1.OverrideSourcePauseAnnotation,
+			Reason: autoscalerv1alpha1.PausedAnnotation + " annotation is set",
+		}
+	case pa.Spec.Override != nil && now.Before(pa.Spec.Override.Until.Time):
+		o := pa.Spec.Override
+		replicas, until := o.Replicas, o.Until
+		return &autoscalerv1alpha1.OverrideStatus{
+			Source:   autoscalerv1alpha1.OverrideSourceOverride,
+			Replicas: &replicas,
+			Until:    &until,
+			Reason:   o.Reason,
+		}
+	default:
+		return nil
+	}
+}
+
+// recordOverride updates status.override and the Overridden condition, and
+// emits events when an override starts or ends.
+func (r *PrometheusAutoscalerReconciler) recordOverride(
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+	ov *autoscalerv1alpha1.OverrideStatus,
+) {
+	prev := pa.Status.Override
+	pa.Status.Override = ov
+
+	if ov == nil {
+		if prev != nil {
+			r.Recorder.Eventf(pa, "Normal", "OverrideEnded", "%s ended; resuming autoscaling", prev.Source)
+		}
+		r.setCondition(pa, "Overridden", metav1.ConditionFalse, "NotOverridden",
+			"Replicas are driven by metrics and policy")
+		return
+	}
+
+	if prev == nil || prev.Source != ov.Source {
+		r.Recorder.Eventf(pa, "Normal", string(ov.Source), "%s", describeOverride(ov))
+	}
+	r.setCondition(pa, "Overridden", metav1.ConditionTrue, string(ov.Source), "%s", describeOverride(ov))
+}
+
+// applyOverride finishes an evaluation while an override is active: suspend
+// and pause leave the target alone, spec.override pins its replicas.
+func (r *PrometheusAutoscalerReconciler) applyOverride(
+	ctx context.Context,
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+	target *scaleTarget,
+	ov *autoscalerv1alpha1.OverrideStatus,
+	now time.Time,
+	interval time.Duration,
+) (ctrl.Result, error) {
+	log := r.Logger.WithValues("prometheusautoscaler", pa.Namespace+"/"+pa.Name)
+
+	// Wake up when the override expires rather than at the next interval.
+	requeue := interval
+	if ov.Until != nil {
+		if d := ov.Until.Sub(now); d < requeue {
+			requeue = d
+		}
+	}
+
+	current := target.Scale.Spec.Replicas
+	if ov.Replicas == nil {
+		log.Info("autoscaler is paused", "source", ov.Source)
+		r.setCondition(pa, "Ready", metav1.ConditionTrue, string(ov.Source), "%s", describeOverride(ov))
+		_ = r.Status().Update(ctx, pa)
+		return ctrl.Result{RequeueAfter: requeue}, nil
+	}
+
+	desired := *ov.Replicas
+	pa.Status.DesiredReplicas = &desired
+
+	if pa.Spec.Mode == autoscalerv1alpha1.ModeDryRun || desired == current {
+		r.setCondition(pa, "Ready", metav1.ConditionTrue, string(ov.Source), "%s", describeOverride(ov))
+		_ = r.Status().Update(ctx, pa)
+		return ctrl.Result{RequeueAfter: requeue}, nil
+	}
+
+	if err := r.updateScale(ctx, target, desired); err != nil {
+		log.Error(err, "failed to apply override", "kind", target.GVK.Kind, "desired", desired)
+		r.setCondition(pa, "Ready", metav1.ConditionFalse, "ScaleFailed", err.Error())
+		_ = r.Status().Update(ctx, pa)
+		return ctrl.Result{}, fmt.Errorf("applying override to %s %s: %w", target.GVK.Kind, target.Key, err)
+	}
+
+	pa.Status.LastScaleTime = &metav1.Time{Time: now}
+	telemetry.RecordScale(pa.Namespace, pa.Name, current, desired)
+	r.setCondition(pa, "Ready", metav1.ConditionTrue, "Scaled",
+		"Scaled from %d to %d (override)", current, desired)
+	if err := r.Status().Update(ctx, pa); err != nil {
+		log.Error(err, "failed to update status after override")
+	}
+	r.Recorder.Eventf(pa, "Normal", "Scaled",
+		"Scaled target %s %s from %d to %d (override)",
+		target.GVK.Kind, target.Key, current, desired)
+
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// describeOverride renders an override for conditions and events.
+func describeOverride(ov *autoscalerv1alpha1.OverrideStatus) string {
+	msg := "Autoscaling suspended: " + ov.Reason
+	if ov.Replicas != nil {
+		msg = fmt.Sprintf("Replicas pinned to %d until %s", *ov.Replicas, ov.Until.UTC().Format(time.RFC3339))
+		if ov.Reason != "" {
+			msg += ": " + ov.Reason
+		}
+	}
+	return msg
+}
@@ -0,0 +1,402 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	"github.com/MreliotA/prometheus-policy-autoscaler/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Keys we understand in the Secret referenced by spec.prometheus.authSecretRef.
+const (
+	authSecretUsernameKey  = "username"
+	authSecretPasswordKey  = "password"
+	authSecretTokenKey     = "token"
+	authSecretHeaderPrefix = "header."
+)
+
+// Field indexes mapping Secret/ConfigMap events back to the autoscalers and
+// endpoints that reference them (auth credentials and TLS material).
+const (
+	secretRefsIndexKey    = "spec.prometheus.secretRefs"
+	configMapRefsIndexKey = "spec.prometheus.configMapRefs"
+)
+
+// endpointRefIndexKey maps (Cluster)PrometheusEndpoint events back to the
+// autoscalers using them. Values are "<Kind>/<name>".
+const endpointRefIndexKey = "spec.prometheus.endpointRef"
+
+// prometheusConfig resolves the metrics client configuration for an autoscaler,
+// following spec.prometheus.endpointRef when set and loading credentials and
+// TLS material from the referenced Secrets.
+func (r *PrometheusAutoscalerReconciler) prometheusConfig(
+	ctx context.Context,
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+) (metrics.Config, error) {
+	resolver := configResolver{reader: r.APIReader}
+
+	ref := pa.Spec.Prometheus.EndpointRef
+	if ref == nil {
+		return resolver.resolve(ctx, pa.Namespace, pa.Spec.Prometheus.PrometheusConnection)
+	}
+
+	switch ref.Kind {
+	case "", autoscalerv1alpha1.EndpointKindNamespaced:
+		var ep autoscalerv1alpha1.PrometheusEndpoint
+		key := types.NamespacedName{Namespace: pa.Namespace, Name: ref.Name}
+		if err := r.Get(ctx, key, &ep); err != nil {
+			return metrics.Config{}, fmt.Errorf("getting PrometheusEndpoint %s: %w", key, err)
+		}
+		return resolver.resolve(ctx, ep.Namespace, ep.Spec.PrometheusConnection)
+	case autoscalerv1alpha1.EndpointKindCluster:
+		var ep autoscalerv1alpha1.ClusterPrometheusEndpoint
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name}, &ep); err != nil {
+			return metrics.Config{}, fmt.Errorf("getting ClusterPrometheusEndpoint %s: %w", ref.Name, err)
+		}
+		return resolver.resolve(ctx, r.ClusterResourceNamespace, ep.Spec.PrometheusConnection)
+	default:
+		return metrics.Config{}, fmt.Errorf("unknown endpointRef kind %q", ref.Kind)
+	}
+}
+
+// configResolver turns a PrometheusConnection into a metrics.Config. It reads
+// Secrets and ConfigMaps through an uncached reader so their contents are
+// never held in the controller's informer cache.
+type configResolver struct {
+	reader client.Reader
+}
+
+// resolve builds the client configuration; referenced objects are looked up
+// in namespace.
+func (c configResolver) resolve(
+	ctx context.Context,
+	namespace string,
+	conn autoscalerv1alpha1.PrometheusConnection,
+) (metrics.Config, error) {
+	if conn.URL == "" {
+		return metrics.Config{}, fmt.Errorf("prometheus url is empty")
+	}
+
+	cfg := metrics.Config{
+		Address:           conn.URL,
+		FailoverAddresses: conn.FailoverURLs,
+		Strategy:          metrics.Strategy(conn.Strategy),
+		TenantID:          conn.TenantID,
+		Headers:           conn.Headers,
+		QueryParameters:   conn.QueryParameters,
+	}
+	if t := conn.TimeoutSeconds; t != nil {
+		cfg.Timeout = time.Duration(*t) * time.Second
+	}
+
+	if ref := conn.AuthSecretRef; ref != nil && *ref != "" {
+		auth, err := c.loadAuth(ctx, namespace, *ref)
+		if err != nil {
+			return metrics.Config{}, err
+		}
+		cfg.Auth = auth
+	}
+
+	if tlsSpec := conn.TLS; tlsSpec != nil {
+		tlsCfg, err := c.loadTLS(ctx, namespace, tlsSpec)
+		if err != nil {
+			return metrics.Config{}, err
+		}
+		cfg.TLS = tlsCfg
+	}
+
+	return cfg, nil
+}
+
+// loadTLS resolves the PEM material referenced by the TLS spec.
+func (c configResolver) loadTLS(
+	ctx context.Context,
+	namespace string,
+	spec *autoscalerv1alpha1.TLSConfig,
+) (*metrics.TLSConfig, error) {
+	out := &metrics.TLSConfig{
+		ServerName:         spec.ServerName,
+		InsecureSkipVerify: spec.InsecureSkipVerify,
+	}
+
+	if spec.CA != nil {
+		var err error
+		switch {
+		case spec.CA.Secret != nil:
+			out.CA, err = c.secretKey(ctx, namespace, spec.CA.Secret)
+		case spec.CA.ConfigMap != nil:
+			out.CA, err = c.configMapKey(ctx, namespace, spec.CA.ConfigMap)
+		default:
+			err = fmt.Errorf("tls.ca must reference a secret or a configMap")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if (spec.Cert == nil) != (spec.KeySecret == nil) {
+		return nil, fmt.Errorf("tls.cert and tls.keySecret must be set together")
+	}
+	if spec.Cert != nil {
+		var err error
+		if out.Cert, err = c.secretKey(ctx, namespace, spec.Cert); err != nil {
+			return nil, err
+		}
+		if out.Key, err = c.secretKey(ctx, namespace, spec.KeySecret); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// secretKey reads one key of a Secret through the API reader.
+func (c configResolver) secretKey(
+	ctx context.Context,
+	namespace string,
+	sel *corev1.SecretKeySelector,
+) ([]byte, error) {
+	var secret corev1.Secret
+	key := types.NamespacedName{Namespace: namespace, Name: sel.Name}
+	if err := c.reader.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("reading secret %s: %w", key, err)
+	}
+	data, ok := secret.Data[sel.Key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no key %q", key, sel.Key)
+	}
+	return data, nil
+}
+
+// configMapKey reads one key of a ConfigMap through the API reader.
+func (c configResolver) configMapKey(
+	ctx context.Context,
+	namespace string,
+	sel *corev1.ConfigMapKeySelector,
+) ([]byte, error) {
+	var cm corev1.ConfigMap
+	key := types.NamespacedName{Namespace: namespace, Name: sel.Name}
+	if err := c.reader.Get(ctx, key, &cm); err != nil {
+		return nil, fmt.Errorf("reading configmap %s: %w", key, err)
+	}
+	if data, ok := cm.Data[sel.Key]; ok {
+		return []byte(data), nil
+	}
+	if data, ok := cm.BinaryData[sel.Key]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("configmap %s has no key %q", key, sel.Key)
+}
+
+// loadAuth reads credentials from a Secret. We go through the API reader so
+// Secret contents are never held in the controller's informer cache.
+func (c configResolver) loadAuth(ctx context.Context, namespace, name string) (*metrics.Auth, error) {
+	var secret corev1.Secret
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if err := c.reader.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("reading auth secret %s: %w", key, err)
+	}
+
+	auth := &metrics.Auth{
+		Username:    string(secret.Data[authSecretUsernameKey]),
+		Password:    string(secret.Data[authSecretPasswordKey]),
+		BearerToken: strings.TrimSpace(string(secret.Data[authSecretTokenKey])),
+	}
+
+	for k, v := range secret.Data {
+		if !strings.HasPrefix(k, authSecretHeaderPrefix) {
+			continue
+		}
+		header := strings.TrimPrefix(k, authSecretHeaderPrefix)
+		if header == "" {
+			continue
+		}
+		if auth.Headers == nil {
+			auth.Headers = make(map[string]string)
+		}
+		auth.Headers[header] = strings.TrimSpace(string(v))
+	}
+
+	if auth.Username == "" && auth.Password == "" && auth.BearerToken == "" && len(auth.Headers) == 0 {
+		return nil, fmt.Errorf("auth secret %s has none of the keys %q, %q, %q or %q*",
+			key, authSecretUsernameKey, authSecretPasswordKey, authSecretTokenKey, authSecretHeaderPrefix)
+	}
+
+	return auth, nil
+}
+
+// autoscalersForSecret maps a Secret event to the autoscalers that reference
+// it, directly or through an endpoint.
+func (r *PrometheusAutoscalerReconciler) autoscalersForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	return append(r.autoscalersReferencing(ctx, secretRefsIndexKey, obj),
+		r.autoscalersViaEndpoints(ctx, secretRefsIndexKey, obj)...)
+}
+
+// autoscalersForConfigMap maps a ConfigMap event to the autoscalers that
+// reference it, directly or through an endpoint.
+func (r *PrometheusAutoscalerReconciler) autoscalersForConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	return append(r.autoscalersReferencing(ctx, configMapRefsIndexKey, obj),
+		r.autoscalersViaEndpoints(ctx, configMapRefsIndexKey, obj)...)
+}
+
+// autoscalersViaEndpoints finds the endpoints whose index entry matches obj's
+// name and returns the autoscalers using them. PrometheusEndpoints resolve
+// references in their own namespace, ClusterPrometheusEndpoints in
+// ClusterResourceNamespace.
+func (r *PrometheusAutoscalerReconciler) autoscalersViaEndpoints(
+	ctx context.Context,
+	indexKey string,
+	obj client.Object,
+) []reconcile.Request {
+	var requests []reconcile.Request
+
+	var endpoints autoscalerv1alpha1.PrometheusEndpointList
+	if err := r.List(ctx, &endpoints,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{indexKey: obj.GetName()},
+	); err != nil {
+		r.Logger.Error(err, "failed to list endpoints for referenced object",
+			"index", indexKey, "object", client.ObjectKeyFromObject(obj))
+	}
+	for i := range endpoints.Items {
+		requests = append(requests, r.autoscalersForEndpoint(ctx, &endpoints.Items[i])...)
+	}
+
+	if r.ClusterResourceNamespace == "" || obj.GetNamespace() != r.ClusterResourceNamespace {
+		return requests
+	}
+	var clusterEndpoints autoscalerv1alpha1.ClusterPrometheusEndpointList
+	if err := r.List(ctx, &clusterEndpoints, client.MatchingFields{indexKey: obj.GetName()}); err != nil {
+		r.Logger.Error(err, "failed to list cluster endpoints for referenced object",
+			"index", indexKey, "object", client.ObjectKeyFromObject(obj))
+	}
+	for i := range clusterEndpoints.Items {
+		requests = append(requests, r.autoscalersForEndpoint(ctx, &clusterEndpoints.Items[i])...)
+	}
+	return requests
+}
+
+// autoscalersForEndpoint maps a PrometheusEndpoint or ClusterPrometheusEndpoint
+// event to the autoscalers that reference it.
+func (r *PrometheusAutoscalerReconciler) autoscalersForEndpoint(ctx context.Context, obj client.Object) []reconcile.Request {
+	kind := autoscalerv1alpha1.EndpointKindNamespaced
+	opts := []client.ListOption{client.InNamespace(obj.GetNamespace())}
+	if _, ok := obj.(*autoscalerv1alpha1.ClusterPrometheusEndpoint); ok {
+		kind = autoscalerv1alpha1.EndpointKindCluster
+		opts = nil
+	}
+	opts = append(opts, client.MatchingFields{endpointRefIndexKey: endpointIndexValue(kind, obj.GetName())})
+
+	var list autoscalerv1alpha1.PrometheusAutoscalerList
+	if err := r.List(ctx, &list, opts...); err != nil {
+		r.Logger.Error(err, "failed to list autoscalers for endpoint",
+			"kind", kind, "endpoint", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, pa := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pa)})
+	}
+	return requests
+}
+
+// autoscalersReferencing lists the autoscalers in obj's namespace whose index
+// entry matches obj's name.
+func (r *PrometheusAutoscalerReconciler) autoscalersReferencing(
+	ctx context.Context,
+	indexKey string,
+	obj client.Object,
+) []reconcile.Request {
+	var list autoscalerv1alpha1.PrometheusAutoscalerList
+	if err := r.List(ctx, &list,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{indexKey: obj.GetName()},
+	); err != nil {
+		r.Logger.Error(err, "failed to list autoscalers for referenced object",
+			"index", indexKey, "object", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, pa := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pa)})
+	}
+	return requests
+}
+
+// indexSecretRefs extracts every Secret name an autoscaler or endpoint
+// depends on.
+func indexSecretRefs(obj client.Object) []string {
+	conn, ok := connectionOf(obj)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	if ref := conn.AuthSecretRef; ref != nil && *ref != "" {
+		names = append(names, *ref)
+	}
+	if t := conn.TLS; t != nil {
+		if t.CA != nil && t.CA.Secret != nil {
+			names = append(names, t.CA.Secret.Name)
+		}
+		if t.Cert != nil {
+			names = append(names, t.Cert.Name)
+		}
+		if t.KeySecret != nil {
+			names = append(names, t.KeySecret.Name)
+		}
+	}
+	return names
+}
+
+// indexConfigMapRefs extracts every ConfigMap name an autoscaler or endpoint
+// depends on.
+func indexConfigMapRefs(obj client.Object) []string {
+	conn, ok := connectionOf(obj)
+	if !ok || conn.TLS == nil || conn.TLS.CA == nil || conn.TLS.CA.ConfigMap == nil {
+		return nil
+	}
+	return []string{conn.TLS.CA.ConfigMap.Name}
+}
+
+// connectionOf returns the inline connection settings of an autoscaler or
+// endpoint. Autoscalers using endpointRef have none of their own.
+func connectionOf(obj client.Object) (autoscalerv1alpha1.PrometheusConnection, bool) {
+	switch o := obj.(type) {
+	case *autoscalerv1alpha1.PrometheusAutoscaler:
+		return o.Spec.Prometheus.PrometheusConnection, true
+	case *autoscalerv1alpha1.PrometheusEndpoint:
+		return o.Spec.PrometheusConnection, true
+	case *autoscalerv1alpha1.ClusterPrometheusEndpoint:
+		return o.Spec.PrometheusConnection, true
+	default:
+		return autoscalerv1alpha1.PrometheusConnection{}, false
+	}
+}
+
+// indexEndpointRef extracts the endpoint an autoscaler uses, if any.
+func indexEndpointRef(obj client.Object) []string {
+	pa, ok := obj.(*autoscalerv1alpha1.PrometheusAutoscaler)
+	if !ok || pa.Spec.Prometheus.EndpointRef == nil {
+		return nil
+	}
+	ref := pa.Spec.Prometheus.EndpointRef
+	kind := ref.Kind
+	if kind == "" {
+		kind = autoscalerv1alpha1.EndpointKindNamespaced
+	}
+	return []string{endpointIndexValue(kind, ref.Name)}
+}
+
+func endpointIndexValue(kind autoscalerv1alpha1.EndpointKind, name string) string {
+	return string(kind) + "/" + name
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -11,22 +13,43 @@ import (
 	"github.com/MreliotA/prometheus-policy-autoscaler/pkg/history"
 	"github.com/MreliotA/prometheus-policy-autoscaler/pkg/metrics"
 	"github.com/MreliotA/prometheus-policy-autoscaler/pkg/policy"
-	appsv1 "k8s.io/api/apps/v1"
+	"github.com/MreliotA/prometheus-policy-autoscaler/pkg/telemetry"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // NOTE: These RBAC markers are used by controller-gen to generate RBAC manifests.
 // They do not affect runtime behavior but are very useful when you automate RBAC.
-// +kubebuilder:rbac:groups=autoscaler.laravel.app,resources=prometheusautoscalers,verbs=get;list;watch;update;patch
-// +kubebuilder:rbac:groups=autoscaler.laravel.app,resources=prometheusautoscalers/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=autoscaler.parspack.dev,resources=prometheusautoscalers,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=autoscaler.parspack.dev,resources=prometheusautoscalers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=autoscaler.parspack.dev,resources=prometheusautoscalers/finalizers,verbs=update
+// +kubebuilder:rbac:groups=*,resources=*/scale,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=autoscaler.parspack.dev,resources=prometheusendpoints;clusterprometheusendpoints,verbs=get;list;watch
+
+const (
+	// defaultEvaluationInterval is used when neither the spec nor the
+	// controller flag configure an interval.
+	defaultEvaluationInterval = 30 * time.Second
+
+	// defaultHistoryLimit is the minimum number of decisions we keep per autoscaler.
+	defaultHistoryLimit = 20
+)
 
 // PrometheusAutoscalerReconciler reconciles a PrometheusAutoscaler object.
 type PrometheusAutoscalerReconciler struct {
@@ -35,73 +58,219 @@ type PrometheusAutoscalerReconciler struct {
 	Recorder record.EventRecorder
 	Logger   logr.Logger
 
-	PromClientFactory func(url string) (metrics.Client, error)
+	// APIReader reads Secrets straight from the API server so credentials
+	// do not end up in the shared informer cache.
+	APIReader client.Reader
+
+	// DefaultEvaluationInterval applies to autoscalers that do not set
+	// spec.evaluationIntervalSeconds.
+	DefaultEvaluationInterval time.Duration
+
+	// ClusterResourceNamespace holds the Secrets and ConfigMaps referenced
+	// by ClusterPrometheusEndpoints.
+	ClusterResourceNamespace string
+
+	// MaxConcurrentQueries caps in-flight Prometheus queries across all
+	// autoscalers. Zero means no global limit.
+	MaxConcurrentQueries int
+
+	PromClientFactory func(cfg metrics.Config) (metrics.Client, error)
 	PolicyEngine      policy.Engine
-	HistoryStore      *history.Store
+	HistoryStore      history.Store
+
+	// querySlots enforces MaxConcurrentQueries; nil when unlimited.
+	querySlots chan struct{}
+
+	// appliedReplicas remembers the replicas we last wrote per target (keyed
+	// by targetIndexValue), so our own scale operations don't retrigger an
+	// evaluation through the target watch.
+	appliedReplicas sync.Map
 }
 
 // Reconcile implements the core control loop for PrometheusAutoscaler.
-// It pulls Prometheus metrics, runs the policy engine, and patches
-// the target Deployment's replica count accordingly.
+// It pulls Prometheus metrics, runs the policy engine, and updates the
+// target workload's replica count through its /scale subresource.
 func (r *PrometheusAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Logger.WithValues("prometheusautoscaler", req.NamespacedName)
 
 	var pa autoscalerv1alpha1.PrometheusAutoscaler
 	if err := r.Get(ctx, req.NamespacedName, &pa); err != nil {
 		if apierrors.IsNotFound(err) {
-			// The CR was deleted; nothing left to do.
+			// Autoscalers that were deleted before they got our finalizer
+			// still leave their persisted history behind.
+			if err := r.HistoryStore.Delete(ctx, req.NamespacedName.String()); err != nil {
+				log.Error(err, "failed to delete decision history")
+			}
+			telemetry.Forget(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, fmt.Errorf("getting PrometheusAutoscaler: %w", err)
 	}
 
 	if !pa.ObjectMeta.DeletionTimestamp.IsZero() {
-		// We don't implement any finalizer logic yet.
+		if !controllerutil.ContainsFinalizer(&pa, autoscalerFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.finalize(ctx, &pa); err != nil {
+			log.Error(err, "failed to finalize")
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(&pa, autoscalerFinalizer)
+		if err := r.Update(ctx, &pa); err != nil {
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		telemetry.Forget(pa.Namespace, pa.Name)
 		return ctrl.Result{}, nil
 	}
 
+	// Every evaluation path below ends up here; replicas are exported from
+	// status so fallback, overrides and regular decisions are all covered.
+	start := time.Now()
+	defer func() {
+		telemetry.ObserveEvaluation(pa.Namespace, pa.Name, time.Since(start))
+		if pa.Status.CurrentReplicas != nil && pa.Status.DesiredReplicas != nil {
+			telemetry.ObserveReplicas(pa.Namespace, pa.Name, *pa.Status.CurrentReplicas, *pa.Status.DesiredReplicas)
+		}
+	}()
+
+	if controllerutil.AddFinalizer(&pa, autoscalerFinalizer) {
+		if err := r.Update(ctx, &pa); err != nil {
+			return ctrl.Result{}, fmt.Errorf("adding finalizer: %w", err)
+		}
+	}
+
 	if pa.Status.Conditions == nil {
 		pa.Status.Conditions = []metav1.Condition{}
 	}
 
-	targetKey := types.NamespacedName{
-		Namespace: pa.Spec.TargetRef.Namespace,
-		Name:      pa.Spec.TargetRef.Name,
-	}
+	interval := r.evaluationInterval(&pa)
+	now := time.Now()
 
-	var deploy appsv1.Deployment
-	if err := r.Get(ctx, targetKey, &deploy); err != nil {
-		if apierrors.IsNotFound(err) {
+	target, err := r.getScaleTarget(ctx, &pa)
+	if err != nil {
+		key := targetKey(&pa)
+		switch {
+		case meta.IsNoMatchError(err):
+			r.setCondition(&pa, "TargetFound", metav1.ConditionFalse, "UnknownKind",
+				"Target kind %s/%s is not served by the cluster: %v",
+				pa.Spec.TargetRef.APIVersion, pa.Spec.TargetRef.Kind, err)
+		case apierrors.IsNotFound(err):
+			r.forgetAppliedReplicas(&pa)
 			r.setCondition(&pa, "TargetFound", metav1.ConditionFalse, "NotFound",
-				"Target workload %s/%s not found", targetKey.Namespace, targetKey.Name)
-			_ = r.Status().Update(ctx, &pa)
-			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+				"Target %s %s/%s not found or has no scale subresource",
+				pa.Spec.TargetRef.Kind, key.Namespace, key.Name)
+		default:
+			return ctrl.Result{}, fmt.Errorf("getting scale of target %s %s: %w", pa.Spec.TargetRef.Kind, key, err)
 		}
-		return ctrl.Result{}, fmt.Errorf("getting target deployment: %w", err)
+		_ = r.Status().Update(ctx, &pa)
+		return ctrl.Result{RequeueAfter: interval}, nil
 	}
+	r.setCondition(&pa, "TargetFound", metav1.ConditionTrue, "ScaleResolved",
+		"Resolved scale subresource of %s %s", target.GVK.Kind, target.Key)
 
-	promClient, err := r.PromClientFactory(pa.Spec.Prometheus.URL)
+	// Two autoscalers driving the same target fight on every evaluation, so
+	// we step back entirely, including fallback.
+	conflict, err := r.findConflict(ctx, &pa, target)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("checking for conflicting autoscalers: %w", err)
+	}
+	if conflict != "" {
+		log.Info("not scaling because of a conflicting autoscaler", "conflict", conflict)
+		r.reportConflict(&pa, conflict)
+		_ = r.Status().Update(ctx, &pa)
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
+	r.setCondition(&pa, "AbleToScale", metav1.ConditionTrue, "NoConflict",
+		"No other autoscaler targets %s %s", target.GVK.Kind, target.Key)
+	if pa.Status.OriginalReplicas == nil {
+		original := target.Scale.Spec.Replicas
+		pa.Status.OriginalReplicas = &original
+	}
+
+	// Decisions are based on the requested replicas; status reports what the
+	// workload actually runs.
+	currentReplicas := target.Scale.Spec.Replicas
+	observedReplicas := target.Scale.Status.Replicas
+	pa.Status.CurrentReplicas = &observedReplicas
+	pa.Status.Selector = target.Scale.Status.Selector
+
+	// Suspend, pause and manual overrides bypass metrics and policy entirely.
+	ov := activeOverride(&pa, now)
+	r.recordOverride(&pa, ov)
+	if ov != nil {
+		return r.applyOverride(ctx, &pa, target, ov, now, interval)
+	}
+
+	// Configuration errors (missing Secrets, bad TLS material) are mistakes
+	// to fix, not an unavailable Prometheus, so they don't count towards
+	// fallback.
+	promConfig, err := r.prometheusConfig(ctx, &pa)
+	if err != nil {
+		log.Error(err, "failed to resolve Prometheus configuration")
+		r.setCondition(&pa, "PrometheusAvailable", metav1.ConditionFalse, "ConfigError", err.Error())
+		_ = r.Status().Update(ctx, &pa)
+		return ctrl.Result{RequeueAfter: retryInterval(interval)}, nil
+	}
+
+	promClient, err := r.PromClientFactory(promConfig)
 	if err != nil {
 		r.setCondition(&pa, "PrometheusAvailable", metav1.ConditionFalse, "ClientError", err.Error())
 		_ = r.Status().Update(ctx, &pa)
-		return ctrl.Result{RequeueAfter: time.Minute}, nil
+		return ctrl.Result{RequeueAfter: retryInterval(interval)}, nil
+	}
+
+	qc := r.queryContextFor(ctx, &pa, target, currentReplicas)
+	results := r.queryMetrics(ctx, &pa, promClient, qc, now)
+	samples := results.Samples
+	pa.Status.Metrics = results.Statuses
+	pa.Status.FailingMetrics = results.Failing
+	pa.Status.ServedBy = results.ServedBy
+
+	switch {
+	case len(results.Failing) == 0:
+		r.setCondition(&pa, "PrometheusAvailable", metav1.ConditionTrue, "QueriesSucceeded",
+			"All %d metric queries succeeded", len(pa.Spec.Metrics))
+	case results.CircuitOpen != nil:
+		r.setCondition(&pa, "PrometheusAvailable", metav1.ConditionFalse, "CircuitOpen",
+			"%v", results.CircuitOpen)
+	case len(results.Failing) == len(pa.Spec.Metrics):
+		r.setCondition(&pa, "PrometheusAvailable", metav1.ConditionFalse, "QueryError",
+			"All metric queries failed: %s", strings.Join(results.Failing, ", "))
+	default:
+		r.setCondition(&pa, "PrometheusAvailable", metav1.ConditionFalse, "PartialQueryError",
+			"Some metric queries failed: %s", strings.Join(results.Failing, ", "))
 	}
 
-	samples := make(map[string]float64)
-	for _, ms := range pa.Spec.Metrics {
-		val, err := promClient.QueryVector(ctx, ms.PromQL)
+	if len(results.Warnings) > 0 {
+		r.setCondition(&pa, "QueryWarnings", metav1.ConditionTrue, "WarningsReturned",
+			"%s", warningsMessage(results.Warnings))
+	} else {
+		r.setCondition(&pa, "QueryWarnings", metav1.ConditionFalse, "NoWarnings",
+			"Prometheus returned no warnings")
+	}
+
+	// An evaluation where every query failed, at least one of them because
+	// Prometheus could not answer, counts towards fallback. Failures that
+	// only come from the spec (templates, selection, sample validation)
+	// neither count nor reset the counter; they show up in the
+	// PrometheusAvailable condition and status.metrics. Until fallback
+	// engages, onError policies still get their say below.
+	allFailed := len(pa.Spec.Metrics) > 0 && len(results.Failing) == len(pa.Spec.Metrics)
+	if allFailed && results.EndpointFailure {
+		active, err := r.recordEvaluationFailure(ctx, &pa, target, now)
 		if err != nil {
-			log.Error(err, "failed to query Prometheus", "metric", ms.Name, "promql", ms.PromQL)
-			r.setCondition(&pa, "PrometheusAvailable", metav1.ConditionFalse, "QueryError", err.Error())
+			r.setCondition(&pa, "Ready", metav1.ConditionFalse, "ScaleFailed", err.Error())
 			_ = r.Status().Update(ctx, &pa)
-			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+			return ctrl.Result{}, err
 		}
-		samples[ms.Name] = val
-	}
-
-	currentReplicas := int32(1)
-	if deploy.Spec.Replicas != nil {
-		currentReplicas = *deploy.Spec.Replicas
+		if active {
+			if err := r.Status().Update(ctx, &pa); err != nil {
+				log.Error(err, "failed to update status in fallback")
+			}
+			return ctrl.Result{RequeueAfter: interval}, nil
+		}
+	} else if !allFailed {
+		r.recordEvaluationSuccess(&pa)
 	}
 
 	var lastScaleTime *time.Time
@@ -110,14 +279,20 @@ func (r *PrometheusAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl
 		lastScaleTime = &t
 	}
 
-	historyKey := fmt.Sprintf("%s/%s", pa.Namespace, pa.Name)
-	hist := r.HistoryStore.Get(historyKey)
+	// Without history the stabilization window starts empty and we could scale
+	// down aggressively, so a failed load aborts this evaluation.
+	historyKey := req.NamespacedName.String()
+	hist, err := r.HistoryStore.Get(ctx, historyKey)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("loading decision history: %w", err)
+	}
 
 	input := policy.Input{
 		CurrentReplicas: currentReplicas,
 		Spec:            pa.Spec,
 		Samples:         samples,
-		Now:             time.Now(),
+		Failures:        results.Failures,
+		Now:             now,
 		LastScaleTime:   lastScaleTime,
 		History:         hist,
 	}
@@ -127,23 +302,46 @@ func (r *PrometheusAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl
 		log.Error(err, "policy engine failed")
 		r.setCondition(&pa, "SpecValid", metav1.ConditionFalse, "PolicyError", err.Error())
 		_ = r.Status().Update(ctx, &pa)
-		return ctrl.Result{RequeueAfter: time.Minute}, nil
+		return ctrl.Result{RequeueAfter: retryInterval(interval)}, nil
 	}
 
 	desired := decision.DesiredReplicas
 
-	// Update in-memory history with the latest decision.
-	r.HistoryStore.Append(historyKey, policy.HistorySample{
+	if len(decision.Vetoes) > 0 {
+		log.Info("scaling vetoed by gate metrics", "gates", decision.Vetoes)
+		r.setCondition(&pa, "Gated", metav1.ConditionTrue, "GateTripped",
+			"Scaling vetoed by %s", strings.Join(decision.Vetoes, ", "))
+	} else {
+		r.setCondition(&pa, "Gated", metav1.ConditionFalse, "NoGateTripped",
+			"No gate metric is above its threshold")
+	}
+
+	if decision.HoldReason != "" {
+		log.Info("holding replicas because of failing metrics", "reason", decision.HoldReason)
+		r.setCondition(&pa, "MetricsHealthy", metav1.ConditionFalse, "Held",
+			"Holding current replicas: %s", decision.HoldReason)
+	} else if len(results.Failing) > 0 {
+		r.setCondition(&pa, "MetricsHealthy", metav1.ConditionFalse, "PartialFailure",
+			"Acting without failing metrics: %s", strings.Join(results.Failing, ", "))
+	} else {
+		r.setCondition(&pa, "MetricsHealthy", metav1.ConditionTrue, "AllHealthy",
+			"All metrics returned a sample")
+	}
+
+	// Record the latest decision in the (persistent) history.
+	if err := r.HistoryStore.Append(ctx, historyKey, policy.HistorySample{
 		Timestamp:       input.Now,
 		DesiredReplicas: desired,
-	}, 20)
+	}, historyLimit(&pa, interval)); err != nil {
+		log.Error(err, "failed to record decision history")
+	}
 
 	sampleJSON, _ := json.Marshal(samples)
 	pa.Status.LastPrometheusSample = string(sampleJSON)
 	pa.Status.DesiredReplicas = &desired
-	pa.Status.CurrentReplicas = &currentReplicas
+	telemetry.ObserveDecision(pa.Namespace, pa.Name, samples, decision.MetricDesired, decision.CooldownActive)
 
-	// DryRun mode: compute decisions but do not touch the target Deployment.
+	// DryRun mode: compute decisions but do not touch the target workload.
 	if pa.Spec.Mode == autoscalerv1alpha1.ModeDryRun {
 		log.Info("dry-run mode: not applying scaling", "current", currentReplicas, "desired", desired)
 		r.setCondition(&pa, "Ready", metav1.ConditionTrue, "DryRun",
@@ -151,7 +349,7 @@ func (r *PrometheusAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl
 		if err := r.Status().Update(ctx, &pa); err != nil {
 			log.Error(err, "failed to update status in dry-run mode")
 		}
-		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		return ctrl.Result{RequeueAfter: interval}, nil
 	}
 
 	// If nothing changed, just refresh status and requeue later.
@@ -162,22 +360,19 @@ func (r *PrometheusAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl
 		if err := r.Status().Update(ctx, &pa); err != nil {
 			log.Error(err, "failed to update status in steady state")
 		}
-		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		return ctrl.Result{RequeueAfter: interval}, nil
 	}
 
-	// Patch target Deployment with the new replica count.
-	patched := deploy.DeepCopy()
-	patched.Spec.Replicas = &desired
-
-	if err := r.Patch(ctx, patched, client.MergeFrom(&deploy)); err != nil {
-		log.Error(err, "failed to patch target deployment", "desired", desired)
+	// Update the target's scale subresource with the new replica count.
+	if err := r.updateScale(ctx, target, desired); err != nil {
+		log.Error(err, "failed to scale target", "kind", target.GVK.Kind, "desired", desired)
 		r.setCondition(&pa, "Ready", metav1.ConditionFalse, "ScaleFailed", err.Error())
 		_ = r.Status().Update(ctx, &pa)
-		return ctrl.Result{}, fmt.Errorf("patching target deployment: %w", err)
+		return ctrl.Result{}, fmt.Errorf("updating scale of target %s %s: %w", target.GVK.Kind, target.Key, err)
 	}
 
-	now := metav1.Now()
-	pa.Status.LastScaleTime = &now
+	pa.Status.LastScaleTime = &metav1.Time{Time: now}
+	telemetry.RecordScale(pa.Namespace, pa.Name, currentReplicas, desired)
 	r.setCondition(&pa, "Ready", metav1.ConditionTrue, "Scaled",
 		"Scaled from %d to %d", currentReplicas, desired)
 	if err := r.Status().Update(ctx, &pa); err != nil {
@@ -186,10 +381,44 @@ func (r *PrometheusAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl
 
 	// Emit a Kubernetes Event for easy tracing in kubectl describe.
 	r.Recorder.Eventf(&pa, "Normal", "Scaled",
-		"Scaled target %s/%s from %d to %d",
-		targetKey.Namespace, targetKey.Name, currentReplicas, desired)
+		"Scaled target %s %s from %d to %d",
+		target.GVK.Kind, target.Key, currentReplicas, desired)
+
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// evaluationInterval returns how often this autoscaler should be evaluated.
+func (r *PrometheusAutoscalerReconciler) evaluationInterval(pa *autoscalerv1alpha1.PrometheusAutoscaler) time.Duration {
+	if s := pa.Spec.EvaluationIntervalSeconds; s != nil && *s > 0 {
+		return time.Duration(*s) * time.Second
+	}
+	if r.DefaultEvaluationInterval > 0 {
+		return r.DefaultEvaluationInterval
+	}
+	return defaultEvaluationInterval
+}
 
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+// retryInterval is used after configuration-type errors that are unlikely to
+// resolve within one short evaluation interval.
+func retryInterval(interval time.Duration) time.Duration {
+	if interval < time.Minute {
+		return time.Minute
+	}
+	return interval
+}
+
+// historyLimit returns how many decisions to keep so the stabilization window
+// is fully covered at the configured evaluation interval.
+func historyLimit(pa *autoscalerv1alpha1.PrometheusAutoscaler, interval time.Duration) int {
+	limit := defaultHistoryLimit
+	if b := pa.Spec.Behavior; b != nil && b.StabilizationWindowSeconds != nil && interval > 0 {
+		window := time.Duration(*b.StabilizationWindowSeconds) * time.Second
+		// One sample per tick in the window, plus the one at the window edge.
+		if needed := int(window/interval) + 2; needed > limit {
+			limit = needed
+		}
+	}
+	return limit
 }
 
 // setCondition is a small helper to keep condition updates consistent.
@@ -201,7 +430,7 @@ func (r *PrometheusAutoscalerReconciler) setCondition(
 	args ...interface{},
 ) {
 	msg := fmt.Sprintf(msgFmt, args...)
-	metav1.SetStatusCondition(&pa.Status.Conditions, metav1.Condition{
+	meta.SetStatusCondition(&pa.Status.Conditions, metav1.Condition{
 		Type:               condType,
 		Status:             status,
 		Reason:             reason,
@@ -212,7 +441,75 @@ func (r *PrometheusAutoscalerReconciler) setCondition(
 
 // SetupWithManager wires this reconciler into the controller-runtime manager.
 func (r *PrometheusAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&autoscalerv1alpha1.PrometheusAutoscaler{}).
-		Complete(r)
+	if r.MaxConcurrentQueries > 0 {
+		r.querySlots = make(chan struct{}, r.MaxConcurrentQueries)
+	}
+
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(),
+		&autoscalerv1alpha1.PrometheusAutoscaler{}, secretRefsIndexKey, indexSecretRefs); err != nil {
+		return fmt.Errorf("indexing secret references: %w", err)
+	}
+	if err := indexer.IndexField(context.Background(),
+		&autoscalerv1alpha1.PrometheusAutoscaler{}, configMapRefsIndexKey, indexConfigMapRefs); err != nil {
+		return fmt.Errorf("indexing configmap references: %w", err)
+	}
+	// Endpoints carry their own credentials and TLS material; a change there
+	// reaches the autoscalers through endpointRefIndexKey.
+	for _, ep := range []client.Object{
+		&autoscalerv1alpha1.PrometheusEndpoint{},
+		&autoscalerv1alpha1.ClusterPrometheusEndpoint{},
+	} {
+		if err := indexer.IndexField(context.Background(), ep, secretRefsIndexKey, indexSecretRefs); err != nil {
+			return fmt.Errorf("indexing endpoint secret references: %w", err)
+		}
+		if err := indexer.IndexField(context.Background(), ep, configMapRefsIndexKey, indexConfigMapRefs); err != nil {
+			return fmt.Errorf("indexing endpoint configmap references: %w", err)
+		}
+	}
+	if err := indexer.IndexField(context.Background(),
+		&autoscalerv1alpha1.PrometheusAutoscaler{}, endpointRefIndexKey, indexEndpointRef); err != nil {
+		return fmt.Errorf("indexing endpoint references: %w", err)
+	}
+	if err := indexer.IndexField(context.Background(),
+		&autoscalerv1alpha1.PrometheusAutoscaler{}, targetRefIndexKey, indexTargetRef); err != nil {
+		return fmt.Errorf("indexing target references: %w", err)
+	}
+
+	// Our own status writes must not trigger another evaluation, otherwise
+	// autoscalers are re-evaluated continuously instead of every interval;
+	// annotation changes still pass for the pause annotation.
+	//
+	// Secrets and ConfigMaps are watched metadata-only: we only need to know
+	// that a referenced object changed, contents are read in prometheusConfig.
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&autoscalerv1alpha1.PrometheusAutoscaler{},
+			builder.WithPredicates(predicate.Or(
+				predicate.GenerationChangedPredicate{},
+				predicate.AnnotationChangedPredicate{},
+			))).
+		Watches(&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.autoscalersForSecret),
+			builder.OnlyMetadata).
+		Watches(&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.autoscalersForConfigMap),
+			builder.OnlyMetadata).
+		Watches(&autoscalerv1alpha1.PrometheusEndpoint{},
+			handler.EnqueueRequestsFromMapFunc(r.autoscalersForEndpoint),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&autoscalerv1alpha1.ClusterPrometheusEndpoint{},
+			handler.EnqueueRequestsFromMapFunc(r.autoscalersForEndpoint),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+
+	// Targets are watched metadata-only too; targetEventHandler tells our own
+	// replica changes apart from manual edits.
+	for _, gvk := range watchedTargetKinds {
+		b = b.Watches(targetWatchObject(gvk), r.targetEventHandler(gvk), builder.OnlyMetadata)
+	}
+
+	// HPAs are watched so that removing a conflicting one lets us take over right away.
+	b = b.Watches(&autoscalingv2.HorizontalPodAutoscaler{},
+		handler.EnqueueRequestsFromMapFunc(r.autoscalersForHPA))
+
+	return b.Complete(r)
 }
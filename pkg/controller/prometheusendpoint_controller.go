@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	"github.com/MreliotA/prometheus-policy-autoscaler/pkg/metrics"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// defaultProbeInterval is how often endpoints are probed when unset.
+const defaultProbeInterval = time.Minute
+
+// +kubebuilder:rbac:groups=autoscaler.parspack.dev,resources=prometheusendpoints;clusterprometheusendpoints,verbs=get;list;watch
+// +kubebuilder:rbac:groups=autoscaler.parspack.dev,resources=prometheusendpoints/status;clusterprometheusendpoints/status,verbs=get;update;patch
+
+// PrometheusEndpointReconciler probes PrometheusEndpoints and
+// ClusterPrometheusEndpoints and reports their reachability and build info.
+type PrometheusEndpointReconciler struct {
+	client.Client
+
+	// APIReader reads referenced Secrets without caching them.
+	APIReader client.Reader
+
+	// ClusterResourceNamespace holds the Secrets and ConfigMaps referenced
+	// by ClusterPrometheusEndpoints.
+	ClusterResourceNamespace string
+
+	// ProbeInterval is how often every endpoint is probed.
+	ProbeInterval time.Duration
+
+	PromClientFactory func(cfg metrics.Config) (metrics.Client, error)
+}
+
+// reconcileNamespaced probes a PrometheusEndpoint.
+func (r *PrometheusEndpointReconciler) reconcileNamespaced(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var ep autoscalerv1alpha1.PrometheusEndpoint
+	if err := r.Get(ctx, req.NamespacedName, &ep); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	r.probe(ctx, ep.Namespace, ep.Generation, ep.Spec, &ep.Status)
+	if err := r.Status().Update(ctx, &ep); err != nil && !apierrors.IsConflict(err) {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.probeInterval()}, nil
+}
+
+// reconcileCluster probes a ClusterPrometheusEndpoint.
+func (r *PrometheusEndpointReconciler) reconcileCluster(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var ep autoscalerv1alpha1.ClusterPrometheusEndpoint
+	if err := r.Get(ctx, req.NamespacedName, &ep); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	r.probe(ctx, r.ClusterResourceNamespace, ep.Generation, ep.Spec, &ep.Status)
+	if err := r.Status().Update(ctx, &ep); err != nil && !apierrors.IsConflict(err) {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.probeInterval()}, nil
+}
+
+// probe resolves the connection, fetches build info and records the outcome.
+func (r *PrometheusEndpointReconciler) probe(
+	ctx context.Context,
+	namespace string,
+	generation int64,
+	spec autoscalerv1alpha1.PrometheusEndpointSpec,
+	status *autoscalerv1alpha1.PrometheusEndpointStatus,
+) {
+	status.ObservedGeneration = generation
+	status.LastProbeTime = &metav1.Time{Time: time.Now()}
+
+	setReachable := func(s metav1.ConditionStatus, reason, msg string) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               "Reachable",
+			Status:             s,
+			Reason:             reason,
+			Message:            msg,
+			ObservedGeneration: generation,
+		})
+	}
+
+	cfg, err := configResolver{reader: r.APIReader}.resolve(ctx, namespace, spec.PrometheusConnection)
+	if err != nil {
+		setReachable(metav1.ConditionFalse, "ConfigError", err.Error())
+		return
+	}
+	promClient, err := r.PromClientFactory(cfg)
+	if err != nil {
+		setReachable(metav1.ConditionFalse, "ClientError", err.Error())
+		return
+	}
+
+	info, err := promClient.BuildInfo(ctx)
+	switch {
+	case errors.Is(err, metrics.ErrCircuitOpen):
+		setReachable(metav1.ConditionFalse, "CircuitOpen", err.Error())
+	case err != nil:
+		setReachable(metav1.ConditionFalse, "ProbeFailed", err.Error())
+	case info.Version == "":
+		status.BuildInfo = nil
+		setReachable(metav1.ConditionTrue, "QuerySucceeded", "Endpoint answers queries but does not report build info")
+	default:
+		status.BuildInfo = &autoscalerv1alpha1.PrometheusBuildInfo{
+			Version:   info.Version,
+			Revision:  info.Revision,
+			Branch:    info.Branch,
+			GoVersion: info.GoVersion,
+		}
+		setReachable(metav1.ConditionTrue, "BuildInfoFetched", "Endpoint reports version "+info.Version)
+	}
+}
+
+func (r *PrometheusEndpointReconciler) probeInterval() time.Duration {
+	if r.ProbeInterval > 0 {
+		return r.ProbeInterval
+	}
+	return defaultProbeInterval
+}
+
+// SetupWithManager registers one controller per endpoint kind.
+func (r *PrometheusEndpointReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Our own status updates must not trigger another probe; periodic
+	// probing is driven by RequeueAfter.
+	if err := ctrl.NewControllerManagedBy(mgr).
+		For(&autoscalerv1alpha1.PrometheusEndpoint{},
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(reconcile.Func(r.reconcileNamespaced)); err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&autoscalerv1alpha1.ClusterPrometheusEndpoint{},
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(reconcile.Func(r.reconcileCluster))
+}
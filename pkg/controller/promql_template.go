@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// queryContext is the data PromQL templates are rendered with.
+type queryContext struct {
+	Namespace       string
+	TargetName      string
+	TargetKind      string
+	CurrentReplicas int32
+	Labels          map[string]string
+	Variables       map[string]string
+
+	// labelsErr is why Labels could not be read; only templates that use
+	// .Labels fail because of it.
+	labelsErr error
+}
+
+// queryContextFor collects the template data for one evaluation. The target's
+// labels are only fetched when a query actually references them.
+func (r *PrometheusAutoscalerReconciler) queryContextFor(
+	ctx context.Context,
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+	target *scaleTarget,
+	currentReplicas int32,
+) queryContext {
+	qc := queryContext{
+		Namespace:       target.Key.Namespace,
+		TargetName:      target.Key.Name,
+		TargetKind:      target.GVK.Kind,
+		CurrentReplicas: currentReplicas,
+		Variables:       pa.Spec.Variables,
+	}
+
+	for _, ms := range pa.Spec.Metrics {
+		if usesLabels(ms.PromQL) {
+			qc.Labels, qc.labelsErr = r.targetLabels(ctx, target)
+			break
+		}
+	}
+	return qc
+}
+
+// targetLabels reads the target's labels through the API reader, so we don't
+// start an informer for every kind that is being autoscaled.
+func (r *PrometheusAutoscalerReconciler) targetLabels(ctx context.Context, target *scaleTarget) (map[string]string, error) {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(target.GVK)
+	if err := r.APIReader.Get(ctx, target.Key, obj); err != nil {
+		return nil, fmt.Errorf("reading labels of %s %s: %w", target.GVK.Kind, target.Key, err)
+	}
+	return obj.GetLabels(), nil
+}
+
+// templateFuncs are available to PromQL templates. quote renders a value as
+// a PromQL string literal, escaping quotes and backslashes, so label values
+// and variables can't break out of a matcher:
+// {app={{ quote .Labels.app }}}.
+var templateFuncs = template.FuncMap{
+	"quote": strconv.Quote,
+}
+
+// renderPromQL executes the metric's PromQL as a Go template. Queries without
+// template actions are returned unchanged.
+func renderPromQL(ms autoscalerv1alpha1.MetricSpec, qc queryContext) (string, error) {
+	if !strings.Contains(ms.PromQL, "{{") {
+		return ms.PromQL, nil
+	}
+	if usesLabels(ms.PromQL) && qc.labelsErr != nil {
+		return "", qc.labelsErr
+	}
+
+	tmpl, err := template.New(ms.Name).Option("missingkey=error").Funcs(templateFuncs).Parse(ms.PromQL)
+	if err != nil {
+		return "", fmt.Errorf("parsing promQL template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, qc); err != nil {
+		return "", fmt.Errorf("rendering promQL template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// usesLabels reports whether a template references the target's labels.
+func usesLabels(promql string) bool {
+	return strings.Contains(promql, "{{") && strings.Contains(promql, ".Labels")
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// scaleGVK is the kind returned by every /scale subresource.
+var scaleGVK = autoscalingv1.SchemeGroupVersion.WithKind("Scale")
+
+// scaleTarget is the workload behind spec.targetRef, resolved through its
+// /scale subresource so any kind implementing scale can be driven.
+type scaleTarget struct {
+	GVK   schema.GroupVersionKind
+	Key   types.NamespacedName
+	Scale autoscalingv1.Scale
+}
+
+// object returns an unstructured stub that addresses the target workload.
+func (t *scaleTarget) object() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(t.GVK)
+	obj.SetNamespace(t.Key.Namespace)
+	obj.SetName(t.Key.Name)
+	return obj
+}
+
+// targetKey returns the namespaced name of the target; an empty namespace
+// means the autoscaler's own namespace.
+func targetKey(pa *autoscalerv1alpha1.PrometheusAutoscaler) types.NamespacedName {
+	ns := pa.Spec.TargetRef.Namespace
+	if ns == "" {
+		ns = pa.Namespace
+	}
+	return types.NamespacedName{Namespace: ns, Name: pa.Spec.TargetRef.Name}
+}
+
+// targetGVK parses apiVersion/kind from the target reference and checks that
+// the REST mapper knows about it.
+func (r *PrometheusAutoscalerReconciler) targetGVK(ref autoscalerv1alpha1.TargetRef) (schema.GroupVersionKind, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("parsing targetRef.apiVersion %q: %w", ref.APIVersion, err)
+	}
+	gvk := gv.WithKind(ref.Kind)
+
+	if _, err := r.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("resolving %s: %w", gvk, err)
+	}
+	return gvk, nil
+}
+
+// getScaleTarget reads the /scale subresource of the target workload.
+func (r *PrometheusAutoscalerReconciler) getScaleTarget(
+	ctx context.Context,
+	pa *autoscalerv1alpha1.PrometheusAutoscaler,
+) (*scaleTarget, error) {
+	gvk, err := r.targetGVK(pa.Spec.TargetRef)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &scaleTarget{GVK: gvk, Key: targetKey(pa)}
+	if err := r.readScale(ctx, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// readScale fills target.Scale from the /scale subresource.
+func (r *PrometheusAutoscalerReconciler) readScale(ctx context.Context, target *scaleTarget) error {
+	// The unstructured client needs an unstructured body for subresources too.
+	raw := &unstructured.Unstructured{}
+	raw.SetGroupVersionKind(scaleGVK)
+	if err := r.SubResource("scale").Get(ctx, target.object(), raw); err != nil {
+		return err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw.Object, &target.Scale); err != nil {
+		return fmt.Errorf("decoding scale of %s %s: %w", target.GVK.Kind, target.Key, err)
+	}
+	return nil
+}
+
+// updateScale writes a new replica count through the /scale subresource.
+// The resourceVersion read in getScaleTarget guards against lost updates.
+func (r *PrometheusAutoscalerReconciler) updateScale(ctx context.Context, target *scaleTarget, replicas int32) error {
+	scale := target.Scale.DeepCopy()
+	scale.Spec.Replicas = replicas
+
+	body, err := runtime.DefaultUnstructuredConverter.ToUnstructured(scale)
+	if err != nil {
+		return fmt.Errorf("encoding scale: %w", err)
+	}
+	raw := &unstructured.Unstructured{Object: body}
+	raw.SetGroupVersionKind(scaleGVK)
+
+	// Recorded before the write, so the resulting target event already sees
+	// it, and dropped again if the write fails so a stale value cannot hide a
+	// later manual change to the same count.
+	key := targetIndexValue(target.GVK.GroupKind(), target.Key.Namespace, target.Key.Name)
+	r.appliedReplicas.Store(key, replicas)
+
+	if err := r.SubResource("scale").Update(ctx, target.object(), client.WithSubResourceBody(raw)); err != nil {
+		r.appliedReplicas.Delete(key)
+		return err
+	}
+
+	target.Scale.Spec.Replicas = replicas
+	return nil
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+
+	autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// targetRefIndexKey indexes autoscalers by "group/kind/namespace/name" of
+// their scale target.
+const targetRefIndexKey = "spec.targetRef"
+
+// watchedTargetKinds are the target kinds whose changes trigger an immediate
+// reconcile. Other kinds implementing /scale are still autoscaled, but drift
+// is only noticed on the next evaluation.
+var watchedTargetKinds = []schema.GroupVersionKind{
+	appsv1.SchemeGroupVersion.WithKind("Deployment"),
+	appsv1.SchemeGroupVersion.WithKind("StatefulSet"),
+}
+
+// targetWatchObject returns the metadata-only object watched for a kind.
+func targetWatchObject(gvk schema.GroupVersionKind) client.Object {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(gvk)
+	return obj
+}
+
+// targetEventHandler enqueues the autoscalers of a target on creation,
+// deletion and spec changes. Metadata-only events don't say what changed, so
+// on a spec change the target's /scale is read: if it still has the replicas
+// we applied ourselves, the change is our own scale operation (or one that
+// doesn't affect replicas) and no second decision is triggered right away.
+// Any other replica change (e.g. kubectl scale) is drift and reconciled
+// immediately.
+func (r *PrometheusAutoscalerReconciler) targetEventHandler(gvk schema.GroupVersionKind) handler.EventHandler {
+	mapTarget := r.autoscalersForTarget(gvk.GroupKind())
+	enqueue := func(requests []reconcile.Request, q workqueue.RateLimitingInterface) {
+		for _, req := range requests {
+			q.Add(req)
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(mapTarget(ctx, e.Object), q)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			r.appliedReplicas.Delete(targetIndexValue(gvk.GroupKind(), e.Object.GetNamespace(), e.Object.GetName()))
+			enqueue(mapTarget(ctx, e.Object), q)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			// Status updates from rollouts don't bump the generation.
+			if e.ObjectOld.GetGeneration() == e.ObjectNew.GetGeneration() {
+				return
+			}
+			requests := mapTarget(ctx, e.ObjectNew)
+			if len(requests) == 0 {
+				return
+			}
+			key := targetIndexValue(gvk.GroupKind(), e.ObjectNew.GetNamespace(), e.ObjectNew.GetName())
+			if applied, ok := r.appliedReplicas.Load(key); ok {
+				target := &scaleTarget{GVK: gvk, Key: client.ObjectKeyFromObject(e.ObjectNew)}
+				if err := r.readScale(ctx, target); err == nil && target.Scale.Spec.Replicas == applied.(int32) {
+					return
+				}
+			}
+			enqueue(requests, q)
+		},
+	}
+}
+
+// forgetAppliedReplicas drops what we remember about an autoscaler's target.
+func (r *PrometheusAutoscalerReconciler) forgetAppliedReplicas(pa *autoscalerv1alpha1.PrometheusAutoscaler) {
+	for _, key := range indexTargetRef(pa) {
+		r.appliedReplicas.Delete(key)
+	}
+}
+
+// autoscalersForTarget returns a map function from events on targets of the
+// given kind to the autoscalers that reference them. The kind is bound up
+// front because metadata-only events don't reliably carry it.
+func (r *PrometheusAutoscalerReconciler) autoscalersForTarget(gk schema.GroupKind) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var list autoscalerv1alpha1.PrometheusAutoscalerList
+		if err := r.List(ctx, &list, client.MatchingFields{
+			targetRefIndexKey: targetIndexValue(gk, obj.GetNamespace(), obj.GetName()),
+		}); err != nil {
+			r.Logger.Error(err, "failed to list autoscalers for target",
+				"kind", gk.Kind, "target", client.ObjectKeyFromObject(obj))
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(list.Items))
+		for _, pa := range list.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pa)})
+		}
+		return requests
+	}
+}
+
+// indexTargetRef extracts the scale target of an autoscaler.
+func indexTargetRef(obj client.Object) []string {
+	pa, ok := obj.(*autoscalerv1alpha1.PrometheusAutoscaler)
+	if !ok {
+		return nil
+	}
+	gv, err := schema.ParseGroupVersion(pa.Spec.TargetRef.APIVersion)
+	if err != nil {
+		return nil
+	}
+	key := targetKey(pa)
+	return []string{targetIndexValue(gv.WithKind(pa.Spec.TargetRef.Kind).GroupKind(), key.Namespace, key.Name)}
+}
+
+// targetIndexValue ignores the version, so autoscalers keep matching when
+// targetRef.apiVersion and the watched version differ.
+func targetIndexValue(gk schema.GroupKind, namespace, name string) string {
+	return gk.Group + "/" + gk.Kind + "/" + namespace + "/" + name
+}
@@ -0,0 +1,198 @@
+package history
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "hash/fnv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/MreliotA/prometheus-policy-autoscaler/pkg/policy"
+    corev1 "k8s.io/api/core/v1"
+    apierrors "k8s.io/apimachinery/pkg/api/errors"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/types"
+    "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+    // configMapPrefix is prepended to every history ConfigMap name.
+    configMapPrefix = "pas-history-"
+
+    // configMapDataKey holds the compact history encoding.
+    configMapDataKey = "history"
+
+    // keyAnnotation records the original "namespace/name" key for humans.
+    keyAnnotation = "autoscaler.parspack.dev/history-key"
+
+    // fieldOwner is our server-side apply field manager.
+    fieldOwner = "prometheus-policy-autoscaler"
+)
+
+// ConfigMapStore persists history in one ConfigMap per autoscaler inside the
+// controller's namespace. An in-memory copy serves reads; the first Get for a
+// key after startup (or after a leader change) loads it from the API server,
+// so a new leader continues exactly where the previous one stopped.
+type ConfigMapStore struct {
+    client    client.Client
+    reader    client.Reader
+    namespace string
+
+    mu    sync.Mutex
+    cache map[string][]policy.HistorySample
+}
+
+// NewConfigMapStore returns a ConfigMapStore writing to the given namespace.
+// reader should bypass the informer cache (e.g. the manager's API reader) so
+// we don't have to cache every ConfigMap in the cluster.
+func NewConfigMapStore(c client.Client, reader client.Reader, namespace string) *ConfigMapStore {
+    return &ConfigMapStore{
+        client:    c,
+        reader:    reader,
+        namespace: namespace,
+        cache:     make(map[string][]policy.HistorySample),
+    }
+}
+
+// Get implements Store.
+func (s *ConfigMapStore) Get(ctx context.Context, key string) ([]policy.HistorySample, error) {
+    s.mu.Lock()
+    cached, ok := s.cache[key]
+    s.mu.Unlock()
+    if ok {
+        return copySamples(cached), nil
+    }
+
+    loaded, err := s.load(ctx, key)
+    if err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    s.cache[key] = loaded
+    s.mu.Unlock()
+
+    return copySamples(loaded), nil
+}
+
+// Append implements Store. The whole bounded history is written back with
+// server-side apply, so no read is needed before the write.
+func (s *ConfigMapStore) Append(ctx context.Context, key string, sample policy.HistorySample, max int) error {
+    current, err := s.Get(ctx, key)
+    if err != nil {
+        return err
+    }
+    updated := appendBounded(current, sample, max)
+
+    if err := s.save(ctx, key, updated); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    s.cache[key] = updated
+    s.mu.Unlock()
+    return nil
+}
+
+// Delete implements Store.
+func (s *ConfigMapStore) Delete(ctx context.Context, key string) error {
+    s.mu.Lock()
+    delete(s.cache, key)
+    s.mu.Unlock()
+
+    cm := &corev1.ConfigMap{
+        ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: configMapName(key)},
+    }
+    if err := s.client.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+        return fmt.Errorf("deleting history configmap for %s: %w", key, err)
+    }
+    return nil
+}
+
+func (s *ConfigMapStore) load(ctx context.Context, key string) ([]policy.HistorySample, error) {
+    var cm corev1.ConfigMap
+    name := types.NamespacedName{Namespace: s.namespace, Name: configMapName(key)}
+    if err := s.reader.Get(ctx, name, &cm); err != nil {
+        if apierrors.IsNotFound(err) {
+            return nil, nil
+        }
+        return nil, fmt.Errorf("loading history configmap %s: %w", name, err)
+    }
+
+    samples, err := decodeSamples(cm.Data[configMapDataKey])
+    if err != nil {
+        return nil, fmt.Errorf("decoding history configmap %s: %w", name, err)
+    }
+    return samples, nil
+}
+
+func (s *ConfigMapStore) save(ctx context.Context, key string, samples []policy.HistorySample) error {
+    encoded, err := encodeSamples(samples)
+    if err != nil {
+        return err
+    }
+
+    cm := &corev1.ConfigMap{
+        TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+        ObjectMeta: metav1.ObjectMeta{
+            Namespace: s.namespace,
+            Name:      configMapName(key),
+            Labels: map[string]string{
+                "app.kubernetes.io/managed-by": fieldOwner,
+                "app.kubernetes.io/component":  "history",
+            },
+            Annotations: map[string]string{keyAnnotation: key},
+        },
+        Data: map[string]string{configMapDataKey: encoded},
+    }
+
+    if err := s.client.Patch(ctx, cm, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership); err != nil {
+        return fmt.Errorf("saving history configmap for %s: %w", key, err)
+    }
+    return nil
+}
+
+// configMapName derives a valid, stable ConfigMap name from "namespace/name".
+// Long keys are truncated and suffixed with a hash to stay unique.
+func configMapName(key string) string {
+    name := configMapPrefix + strings.ReplaceAll(key, "/", ".")
+    if len(name) <= 253 {
+        return name
+    }
+    h := fnv.New32a()
+    _, _ = h.Write([]byte(key))
+    return fmt.Sprintf("%s-%08x", strings.TrimRight(name[:243], ".-"), h.Sum32())
+}
+
+// encodeSamples stores history compactly as [[unixSeconds, replicas], ...].
+func encodeSamples(samples []policy.HistorySample) (string, error) {
+    compact := make([][2]int64, 0, len(samples))
+    for _, h := range samples {
+        compact = append(compact, [2]int64{h.Timestamp.Unix(), int64(h.DesiredReplicas)})
+    }
+    b, err := json.Marshal(compact)
+    if err != nil {
+        return "", fmt.Errorf("encoding history: %w", err)
+    }
+    return string(b), nil
+}
+
+func decodeSamples(data string) ([]policy.HistorySample, error) {
+    if data == "" {
+        return nil, nil
+    }
+    var compact [][2]int64
+    if err := json.Unmarshal([]byte(data), &compact); err != nil {
+        return nil, err
+    }
+    out := make([]policy.HistorySample, 0, len(compact))
+    for _, c := range compact {
+        out = append(out, policy.HistorySample{
+            Timestamp:       time.Unix(c[0], 0),
+            DesiredReplicas: int32(c[1]),
+        })
+    }
+    return out, nil
+}
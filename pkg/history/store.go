@@ -1,46 +1,79 @@
 package history
 
 import (
+    "context"
     "sync"
 
     "github.com/MreliotA/prometheus-policy-autoscaler/pkg/policy"
 )
 
-// Store is a simple in-memory history store keyed by NamespacedName of the policy.
-// For production, you might want to persist history in annotations or an external
-// store when you have multiple controller instances.
-type Store struct {
+// Store keeps the recent decisions of each autoscaler, keyed by the
+// NamespacedName of the policy ("namespace/name"). The policy engine uses it
+// for stabilization windows, so a store that survives controller restarts and
+// leader failover avoids aggressive scale-downs right after a restart.
+type Store interface {
+    // Get returns a copy of the history for a given key.
+    Get(ctx context.Context, key string) ([]policy.HistorySample, error)
+
+    // Append adds a new sample to the history for the given key.
+    // We keep at most "max" samples; older entries are dropped.
+    Append(ctx context.Context, key string, sample policy.HistorySample, max int) error
+
+    // Delete forgets the history of a key, e.g. when the autoscaler is removed.
+    Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore is a simple in-memory Store. History is lost on restart, which
+// is fine for development or when running a single short-lived instance.
+type MemoryStore struct {
     mu   sync.Mutex
     data map[string][]policy.HistorySample
 }
 
-// NewStore returns an initialized Store.
-func NewStore() *Store {
-    return &Store{
+// NewMemoryStore returns an initialized MemoryStore.
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{
         data: make(map[string][]policy.HistorySample),
     }
 }
 
-// Get returns a copy of the history for a given key.
-func (s *Store) Get(key string) []policy.HistorySample {
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]policy.HistorySample, error) {
     s.mu.Lock()
     defer s.mu.Unlock()
 
-    src := s.data[key]
-    out := make([]policy.HistorySample, len(src))
-    copy(out, src)
-    return out
+    return copySamples(s.data[key]), nil
+}
+
+// Append implements Store.
+func (s *MemoryStore) Append(_ context.Context, key string, sample policy.HistorySample, max int) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.data[key] = appendBounded(s.data[key], sample, max)
+    return nil
 }
 
-// Append adds a new sample to the history for the given key.
-// We keep at most "max" samples; older entries are dropped.
-func (s *Store) Append(key string, sample policy.HistorySample, max int) {
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
     s.mu.Lock()
     defer s.mu.Unlock()
 
-    history := append(s.data[key], sample)
-    if len(history) > max {
-        history = history[len(history)-max:]
+    delete(s.data, key)
+    return nil
+}
+
+// appendBounded appends a sample and drops the oldest entries beyond max.
+func appendBounded(history []policy.HistorySample, sample policy.HistorySample, max int) []policy.HistorySample {
+    out := append(copySamples(history), sample)
+    if max > 0 && len(out) > max {
+        out = out[len(out)-max:]
     }
-    s.data[key] = history
+    return out
+}
+
+func copySamples(src []policy.HistorySample) []policy.HistorySample {
+    out := make([]policy.HistorySample, len(src))
+    copy(out, src)
+    return out
 }
@@ -0,0 +1,174 @@
+// Package manager wires the controllers into a controller-runtime manager.
+// Both entry points (main.go and cmd/controller) share it, so their flags and
+// dependencies cannot drift apart.
+package manager
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    autoscalercontroller "github.com/MreliotA/prometheus-policy-autoscaler/pkg/controller"
+    "github.com/MreliotA/prometheus-policy-autoscaler/pkg/history"
+    "github.com/MreliotA/prometheus-policy-autoscaler/pkg/metrics"
+    "github.com/MreliotA/prometheus-policy-autoscaler/pkg/policy"
+    "github.com/MreliotA/prometheus-policy-autoscaler/pkg/telemetry"
+    ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// serviceAccountNamespaceFile holds the pod's namespace when running in-cluster.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Options are the controller settings shared by both entry points.
+type Options struct {
+    // RecorderName is the component name on emitted events.
+    RecorderName string
+
+    HistoryBackend           string
+    HistoryNamespace         string
+    EvaluationInterval       time.Duration
+    PromIdleConnsPerHost     int
+    PromIdleConnTimeout      time.Duration
+    PromClientTTL            time.Duration
+    PromQueryTimeout         time.Duration
+    PromQueryAttempts        int
+    BreakerThreshold         int
+    BreakerOpenDuration      time.Duration
+    MaxConcurrentQueries     int
+    ClusterResourceNamespace string
+    ProbeInterval            time.Duration
+}
+
+// BindFlags registers the shared command-line flags on fs.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+    fs.StringVar(&o.HistoryBackend, "history-backend", "configmap",
+        "Where decision history is kept: configmap (survives restarts and failover) or memory.")
+    fs.StringVar(&o.HistoryNamespace, "history-namespace", os.Getenv("POD_NAMESPACE"),
+        "Namespace for history ConfigMaps. Defaults to POD_NAMESPACE, then the service account namespace.")
+    fs.DurationVar(&o.EvaluationInterval, "default-evaluation-interval", 30*time.Second,
+        "Evaluation interval for autoscalers that do not set spec.evaluationIntervalSeconds.")
+    fs.IntVar(&o.PromIdleConnsPerHost, "prometheus-max-idle-conns-per-host", 10,
+        "Idle keep-alive connections kept per Prometheus host.")
+    fs.DurationVar(&o.PromIdleConnTimeout, "prometheus-idle-conn-timeout", 90*time.Second,
+        "How long an idle connection to Prometheus is kept open.")
+    fs.DurationVar(&o.PromClientTTL, "prometheus-client-ttl", 10*time.Minute,
+        "Drop pooled Prometheus clients that were not used for this long.")
+    fs.DurationVar(&o.PromQueryTimeout, "prometheus-query-timeout", metrics.DefaultQueryTimeout,
+        "Timeout of a single Prometheus query attempt, unless spec.prometheus.timeoutSeconds is set.")
+    fs.IntVar(&o.PromQueryAttempts, "prometheus-query-attempts", metrics.DefaultRetryOptions.MaxAttempts,
+        "Attempts per Prometheus query, retrying 5xx responses, timeouts and connection errors.")
+    fs.IntVar(&o.BreakerThreshold, "prometheus-breaker-failure-threshold", 5,
+        "Consecutive failed queries that open the circuit breaker of a Prometheus endpoint (0 disables it).")
+    fs.DurationVar(&o.BreakerOpenDuration, "prometheus-breaker-open-duration", 30*time.Second,
+        "How long an open circuit breaker rejects queries before probing the endpoint again.")
+    fs.IntVar(&o.MaxConcurrentQueries, "max-concurrent-queries", 64,
+        "Maximum in-flight Prometheus queries across all autoscalers (0 means unlimited).")
+    fs.StringVar(&o.ClusterResourceNamespace, "cluster-resource-namespace", os.Getenv("POD_NAMESPACE"),
+        "Namespace holding Secrets and ConfigMaps referenced by ClusterPrometheusEndpoints.")
+    fs.DurationVar(&o.ProbeInterval, "endpoint-probe-interval", time.Minute,
+        "How often PrometheusEndpoints and ClusterPrometheusEndpoints are probed.")
+}
+
+// SetupControllers adds the Prometheus client pool and both reconcilers to mgr.
+func SetupControllers(mgr ctrl.Manager, o Options) error {
+    log := ctrl.Log.WithName("setup")
+
+    historyStore, err := newHistoryStore(mgr, o)
+    if err != nil {
+        return err
+    }
+
+    // Prometheus clients are pooled across reconciles and autoscalers.
+    promPool := metrics.NewPool(metrics.PoolOptions{
+        MaxIdleConnsPerHost: o.PromIdleConnsPerHost,
+        IdleConnTimeout:     o.PromIdleConnTimeout,
+        ClientTTL:           o.PromClientTTL,
+        QueryTimeout:        o.PromQueryTimeout,
+        Retry: metrics.RetryOptions{
+            MaxAttempts: o.PromQueryAttempts,
+            BaseBackoff: metrics.DefaultRetryOptions.BaseBackoff,
+            MaxBackoff:  metrics.DefaultRetryOptions.MaxBackoff,
+        },
+        Breaker: metrics.BreakerOptions{
+            FailureThreshold: o.BreakerThreshold,
+            OpenDuration:     o.BreakerOpenDuration,
+        },
+        Observer: telemetry.ObserveQuery,
+    })
+    if err := mgr.Add(promPool); err != nil {
+        return fmt.Errorf("adding prometheus client pool: %w", err)
+    }
+
+    clusterNS := o.ClusterResourceNamespace
+    if clusterNS == "" {
+        clusterNS = serviceAccountNamespace()
+    }
+    if clusterNS == "" {
+        log.Info("no cluster resource namespace; ClusterPrometheusEndpoints cannot reference Secrets or ConfigMaps")
+    }
+
+    reconciler := &autoscalercontroller.PrometheusAutoscalerReconciler{
+        Client:   mgr.GetClient(),
+        Scheme:   mgr.GetScheme(),
+        Recorder: mgr.GetEventRecorderFor(o.RecorderName),
+        Logger:   ctrl.Log.WithName("controller").WithName("PrometheusAutoscaler"),
+
+        APIReader: mgr.GetAPIReader(),
+
+        PromClientFactory: promPool.Get,
+        PolicyEngine:      policy.NewEngine(),
+        HistoryStore:      historyStore,
+
+        DefaultEvaluationInterval: o.EvaluationInterval,
+        MaxConcurrentQueries:      o.MaxConcurrentQueries,
+        ClusterResourceNamespace:  clusterNS,
+    }
+    if err := reconciler.SetupWithManager(mgr); err != nil {
+        return fmt.Errorf("creating PrometheusAutoscaler controller: %w", err)
+    }
+
+    endpointReconciler := &autoscalercontroller.PrometheusEndpointReconciler{
+        Client:                   mgr.GetClient(),
+        APIReader:                mgr.GetAPIReader(),
+        ClusterResourceNamespace: clusterNS,
+        ProbeInterval:            o.ProbeInterval,
+        PromClientFactory:        promPool.Get,
+    }
+    if err := endpointReconciler.SetupWithManager(mgr); err != nil {
+        return fmt.Errorf("creating PrometheusEndpoint controller: %w", err)
+    }
+    return nil
+}
+
+// newHistoryStore builds the configured history backend. The configmap
+// backend falls back to the service account namespace; if there is none
+// either, startup fails rather than silently losing history on restart.
+func newHistoryStore(mgr ctrl.Manager, o Options) (history.Store, error) {
+    switch o.HistoryBackend {
+    case "memory":
+        return history.NewMemoryStore(), nil
+    case "configmap":
+        ns := o.HistoryNamespace
+        if ns == "" {
+            ns = serviceAccountNamespace()
+        }
+        if ns == "" {
+            return nil, fmt.Errorf("the configmap history backend needs --history-namespace or POD_NAMESPACE " +
+                "outside a cluster; use --history-backend=memory to keep history in memory")
+        }
+        return history.NewConfigMapStore(mgr.GetClient(), mgr.GetAPIReader(), ns), nil
+    default:
+        return nil, fmt.Errorf("unknown history backend %q", o.HistoryBackend)
+    }
+}
+
+// serviceAccountNamespace returns the pod's namespace when running in-cluster.
+func serviceAccountNamespace() string {
+    b, err := os.ReadFile(serviceAccountNamespaceFile)
+    if err != nil {
+        return ""
+    }
+    return strings.TrimSpace(string(b))
+}
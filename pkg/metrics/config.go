@@ -0,0 +1,262 @@
+package metrics
+
+import (
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/prometheus/client_golang/api"
+)
+
+// Config describes how to reach a single Prometheus endpoint.
+// The reconciler builds it from the autoscaler spec and referenced Secrets.
+type Config struct {
+    // Address is the base URL of the Prometheus HTTP API.
+    Address string
+
+    // FailoverAddresses are further replicas of the same Prometheus, used
+    // according to Strategy. They share every other setting.
+    FailoverAddresses []string
+
+    // Strategy decides how Address and FailoverAddresses are queried.
+    // Empty means StrategyFailover.
+    Strategy Strategy
+
+    // Auth optionally carries credentials injected into every request.
+    Auth *Auth
+
+    // TLS optionally customizes certificate verification and client certs.
+    TLS *TLSConfig
+
+    // Timeout bounds each query attempt. Zero means the client default.
+    Timeout time.Duration
+
+    // TenantID is sent as X-Scope-OrgID for multi-tenant backends.
+    TenantID string
+
+    // Headers are extra, non-secret request headers. Auth headers win.
+    Headers map[string]string
+
+    // QueryParameters are added to the URL of every request.
+    QueryParameters map[string]string
+}
+
+// TLSConfig holds PEM material resolved from Secrets/ConfigMaps.
+type TLSConfig struct {
+    // CA is a PEM bundle; when empty the system roots are used.
+    CA []byte
+
+    // Cert and Key are the PEM client certificate and key for mutual TLS.
+    Cert []byte
+    Key  []byte
+
+    ServerName         string
+    InsecureSkipVerify bool
+}
+
+// build turns the PEM material into a crypto/tls configuration.
+func (c *TLSConfig) build() (*tls.Config, error) {
+    tlsCfg := &tls.Config{
+        MinVersion:         tls.VersionTLS12,
+        ServerName:         c.ServerName,
+        InsecureSkipVerify: c.InsecureSkipVerify,
+    }
+
+    if len(c.CA) > 0 {
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(c.CA) {
+            return nil, fmt.Errorf("no valid certificates found in CA bundle")
+        }
+        tlsCfg.RootCAs = pool
+    }
+
+    if len(c.Cert) > 0 || len(c.Key) > 0 {
+        pair, err := tls.X509KeyPair(c.Cert, c.Key)
+        if err != nil {
+            return nil, fmt.Errorf("loading client certificate: %w", err)
+        }
+        tlsCfg.Certificates = []tls.Certificate{pair}
+    }
+
+    return tlsCfg, nil
+}
+
+// newTransport returns the base transport for a config. Without TLS options
+// we reuse the Prometheus client's default transport.
+func newTransport(tlsCfg *TLSConfig) (http.RoundTripper, error) {
+    if tlsCfg == nil {
+        return api.DefaultRoundTripper, nil
+    }
+    return cloneTransport(tlsCfg)
+}
+
+// cloneTransport returns a private copy of the default transport with the
+// TLS options applied. tlsCfg may be nil.
+func cloneTransport(tlsCfg *TLSConfig) (*http.Transport, error) {
+    base, ok := api.DefaultRoundTripper.(*http.Transport)
+    if !ok {
+        return nil, fmt.Errorf("unexpected default round tripper %T", api.DefaultRoundTripper)
+    }
+
+    transport := base.Clone()
+    if tlsCfg == nil {
+        return transport, nil
+    }
+
+    built, err := tlsCfg.build()
+    if err != nil {
+        return nil, err
+    }
+    transport.TLSClientConfig = built
+    return transport, nil
+}
+
+// fingerprint identifies the TLS material; equal fingerprints can share a
+// transport. Empty for a nil config.
+func (c *TLSConfig) fingerprint() string {
+    if c == nil {
+        return ""
+    }
+    h := sha256.New()
+    writeField(h, "ca", string(c.CA))
+    writeField(h, "cert", string(c.Cert))
+    writeField(h, "key", string(c.Key))
+    writeField(h, "serverName", c.ServerName)
+    writeField(h, "insecure", strconv.FormatBool(c.InsecureSkipVerify))
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprint identifies the whole client configuration, including the
+// credentials, so a rotated Secret yields a different client.
+func (c Config) fingerprint() string {
+    h := sha256.New()
+    writeField(h, "address", c.Address)
+    for i, addr := range c.FailoverAddresses {
+        writeField(h, "failover."+strconv.Itoa(i), addr)
+    }
+    writeField(h, "strategy", string(c.Strategy))
+    writeField(h, "tls", c.TLS.fingerprint())
+    writeField(h, "timeout", c.Timeout.String())
+    writeField(h, "tenant", c.TenantID)
+    writeMap(h, "header.", c.Headers)
+    writeMap(h, "param.", c.QueryParameters)
+    if c.Auth != nil {
+        writeField(h, "username", c.Auth.Username)
+        writeField(h, "password", c.Auth.Password)
+        writeField(h, "token", c.Auth.BearerToken)
+        writeMap(h, "auth.header.", c.Auth.Headers)
+    }
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeMap writes every entry of m in key order.
+func writeMap(w io.Writer, prefix string, m map[string]string) {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    for _, k := range keys {
+        writeField(w, prefix+k, m[k])
+    }
+}
+
+// writeField writes a length-prefixed name/value pair so that adjacent
+// fields can never run into each other.
+func writeField(w io.Writer, name, value string) {
+    fmt.Fprintf(w, "%d:%s=%d:%s;", len(name), name, len(value), value)
+}
+
+// Auth holds credentials loaded from the autoscaler's AuthSecretRef.
+// Any combination may be set; empty fields are ignored.
+type Auth struct {
+    // Username and Password enable HTTP basic auth.
+    Username string
+    Password string
+
+    // BearerToken is sent as "Authorization: Bearer <token>".
+    BearerToken string
+
+    // Headers are arbitrary extra headers, e.g. an API key for an auth proxy.
+    Headers map[string]string
+}
+
+// authRoundTripper injects credentials into outgoing requests.
+type authRoundTripper struct {
+    auth Auth
+    next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+    // RoundTrippers must not mutate the caller's request.
+    req = req.Clone(req.Context())
+
+    if rt.auth.Username != "" || rt.auth.Password != "" {
+        req.SetBasicAuth(rt.auth.Username, rt.auth.Password)
+    }
+    if rt.auth.BearerToken != "" {
+        req.Header.Set("Authorization", "Bearer "+rt.auth.BearerToken)
+    }
+    for k, v := range rt.auth.Headers {
+        req.Header.Set(k, v)
+    }
+
+    return rt.next.RoundTrip(req)
+}
+
+// tenantHeader carries the tenant for Mimir, Cortex and compatible backends.
+const tenantHeader = "X-Scope-OrgID"
+
+// requestOptionsRoundTripper adds the non-secret per-endpoint headers and
+// query parameters from Config.
+type requestOptionsRoundTripper struct {
+    tenantID string
+    headers  map[string]string
+    params   map[string]string
+    next     http.RoundTripper
+}
+
+// newRequestOptionsRoundTripper wraps next if cfg asks for any extras.
+func newRequestOptionsRoundTripper(cfg Config, next http.RoundTripper) http.RoundTripper {
+    if cfg.TenantID == "" && len(cfg.Headers) == 0 && len(cfg.QueryParameters) == 0 {
+        return next
+    }
+    return &requestOptionsRoundTripper{
+        tenantID: cfg.TenantID,
+        headers:  cfg.Headers,
+        params:   cfg.QueryParameters,
+        next:     next,
+    }
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *requestOptionsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+    req = req.Clone(req.Context())
+
+    for k, v := range rt.headers {
+        req.Header.Set(k, v)
+    }
+    if rt.tenantID != "" {
+        req.Header.Set(tenantHeader, rt.tenantID)
+    }
+
+    // The v1 API POSTs the query as a form; parameters in the URL are
+    // merged with it by Prometheus and Thanos alike.
+    if len(rt.params) > 0 {
+        q := req.URL.Query()
+        for k, v := range rt.params {
+            q.Set(k, v)
+        }
+        req.URL.RawQuery = q.Encode()
+    }
+
+    return rt.next.RoundTrip(req)
+}
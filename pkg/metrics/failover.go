@@ -0,0 +1,131 @@
+package metrics
+
+import (
+    "context"
+    "errors"
+    "math"
+    "sync"
+)
+
+// Strategy decides how a Config with several addresses is queried.
+// The names match EndpointStrategy in the API.
+type Strategy string
+
+const (
+    // StrategyFailover queries the addresses in order and uses the first
+    // one that is healthy.
+    StrategyFailover Strategy = "Failover"
+
+    // StrategyCompare queries every address and uses the highest value, so
+    // a replica with gaps after a restart cannot under-report load.
+    StrategyCompare Strategy = "Compare"
+)
+
+// failoverClient spreads queries over several endpoints of the same
+// Prometheus (e.g. an HA pair). Health is tracked per endpoint by the
+// circuit breakers of the underlying clients, so a down replica is skipped
+// without waiting for its timeout once its breaker is open.
+type failoverClient struct {
+    clients  []Client
+    strategy Strategy
+}
+
+// QueryVector implements Client.
+func (f *failoverClient) QueryVector(ctx context.Context, promql string, sel Selection) (Result, error) {
+    return f.run(ctx, func(ctx context.Context, c Client) (Result, error) {
+        return c.QueryVector(ctx, promql, sel)
+    })
+}
+
+// QueryRange implements Client.
+func (f *failoverClient) QueryRange(ctx context.Context, promql string, r Range, sel Selection) (Result, error) {
+    return f.run(ctx, func(ctx context.Context, c Client) (Result, error) {
+        return c.QueryRange(ctx, promql, r, sel)
+    })
+}
+
+// BuildInfo implements Client; it always reports the first healthy endpoint.
+func (f *failoverClient) BuildInfo(ctx context.Context) (BuildInfo, error) {
+    var errs []error
+    for _, c := range f.clients {
+        info, err := c.BuildInfo(ctx)
+        if err == nil || !IsEndpointFailure(err) {
+            return info, err
+        }
+        errs = append(errs, err)
+    }
+    return BuildInfo{}, errors.Join(errs...)
+}
+
+func (f *failoverClient) run(
+    ctx context.Context,
+    query func(ctx context.Context, c Client) (Result, error),
+) (Result, error) {
+    if f.strategy == StrategyCompare {
+        return f.compare(ctx, query)
+    }
+
+    var errs []error
+    for _, c := range f.clients {
+        res, err := query(ctx, c)
+        // Errors caused by the query itself would fail on every replica.
+        if err == nil || !IsEndpointFailure(err) {
+            return res, err
+        }
+        errs = append(errs, err)
+    }
+    return Result{}, errors.Join(errs...)
+}
+
+// compare queries all endpoints concurrently and keeps the highest value,
+// preferring numbers over NaN.
+func (f *failoverClient) compare(
+    ctx context.Context,
+    query func(ctx context.Context, c Client) (Result, error),
+) (Result, error) {
+    results := make([]Result, len(f.clients))
+    errs := make([]error, len(f.clients))
+
+    var wg sync.WaitGroup
+    for i, c := range f.clients {
+        wg.Add(1)
+        go func(i int, c Client) {
+            defer wg.Done()
+            results[i], errs[i] = query(ctx, c)
+        }(i, c)
+    }
+    wg.Wait()
+
+    var (
+        best  Result
+        found bool
+    )
+    for i, err := range errs {
+        if err != nil {
+            continue
+        }
+        if !found || better(results[i].Value, best.Value) {
+            best, found = results[i], true
+        }
+    }
+    if !found {
+        return Result{}, errors.Join(errs...)
+    }
+    return best, nil
+}
+
+// better reports whether v beats the current best value. NaN never wins
+// against a real number, so one replica without data can't hide the others.
+func better(v, best float64) bool {
+    if math.IsNaN(best) {
+        return !math.IsNaN(v)
+    }
+    return v > best
+}
+
+// IsEndpointFailure reports whether err means the endpoint could not answer
+// (circuit open, 5xx, timeouts, connection errors), so another endpoint is
+// worth trying. Errors caused by the query itself return false.
+func IsEndpointFailure(err error) bool {
+    return errors.Is(err, ErrCircuitOpen) || retryable(err)
+}
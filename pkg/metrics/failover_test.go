@@ -0,0 +1,93 @@
+package metrics
+
+import (
+    "context"
+    "errors"
+    "math"
+    "testing"
+
+    v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// fakeClient answers every query with the same result.
+type fakeClient struct {
+    res   Result
+    err   error
+    calls int
+}
+
+func (f *fakeClient) QueryVector(context.Context, string, Selection) (Result, error) {
+    f.calls++
+    return f.res, f.err
+}
+
+func (f *fakeClient) QueryRange(context.Context, string, Range, Selection) (Result, error) {
+    f.calls++
+    return f.res, f.err
+}
+
+func (f *fakeClient) BuildInfo(context.Context) (BuildInfo, error) {
+    return BuildInfo{}, f.err
+}
+
+func answer(endpoint string, v float64) *fakeClient {
+    return &fakeClient{res: Result{Value: v, Endpoint: endpoint}}
+}
+
+func failing(err error) *fakeClient {
+    return &fakeClient{err: err}
+}
+
+func TestFailoverCompare(t *testing.T) {
+    down := &v1.Error{Type: v1.ErrServer}
+
+    tests := []struct {
+        name         string
+        clients      []Client
+        wantEndpoint string
+        wantErr      bool
+    }{
+        {"highest value wins", []Client{answer("a", 3), answer("b", 7), answer("c", 5)}, "b", false},
+        {"failed endpoints are skipped", []Client{failing(down), answer("b", 2)}, "b", false},
+        {"NaN loses against a number", []Client{answer("a", math.NaN()), answer("b", 1)}, "b", false},
+        {"number keeps winning over NaN", []Client{answer("a", 1), answer("b", math.NaN())}, "a", false},
+        {"only NaN", []Client{answer("a", math.NaN()), failing(down)}, "a", false},
+        {"all failed", []Client{failing(down), failing(down)}, "", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            f := &failoverClient{clients: tt.clients, strategy: StrategyCompare}
+            res, err := f.QueryVector(context.Background(), "up", Selection{})
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("QueryVector error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if res.Endpoint != tt.wantEndpoint {
+                t.Errorf("answered by %q, want %q", res.Endpoint, tt.wantEndpoint)
+            }
+        })
+    }
+}
+
+func TestFailoverInOrder(t *testing.T) {
+    down := &v1.Error{Type: v1.ErrServer}
+    badQuery := &v1.Error{Type: v1.ErrBadData}
+
+    // An unavailable endpoint moves on to the next one.
+    second := answer("b", 2)
+    f := &failoverClient{clients: []Client{failing(down), second}, strategy: StrategyFailover}
+    res, err := f.QueryVector(context.Background(), "up", Selection{})
+    if err != nil || res.Endpoint != "b" {
+        t.Errorf("QueryVector = (%+v, %v), want an answer from b", res, err)
+    }
+
+    // A bad query would fail everywhere, so it is returned right away.
+    second = answer("b", 2)
+    f = &failoverClient{clients: []Client{failing(badQuery), second}, strategy: StrategyFailover}
+    if _, err := f.QueryVector(context.Background(), "up", Selection{}); !errors.Is(err, badQuery) {
+        t.Errorf("QueryVector error = %v, want the bad query error", err)
+    }
+    if second.calls != 0 {
+        t.Errorf("second endpoint was queried %d times after a bad query", second.calls)
+    }
+}
@@ -0,0 +1,239 @@
+package metrics
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// PoolOptions tunes connection reuse for a Pool.
+type PoolOptions struct {
+    // MaxIdleConnsPerHost bounds the idle keep-alive connections kept per
+    // Prometheus host and transport.
+    MaxIdleConnsPerHost int
+
+    // IdleConnTimeout closes keep-alive connections idle for this long.
+    IdleConnTimeout time.Duration
+
+    // ClientTTL drops clients (and transports nobody uses any more) that
+    // were not requested for this long, e.g. after a Secret rotation.
+    ClientTTL time.Duration
+
+    // QueryTimeout is used for configs that don't set their own Timeout.
+    QueryTimeout time.Duration
+
+    // Retry bounds retries of transient query failures.
+    Retry RetryOptions
+
+    // Breaker configures the circuit breaker kept per Prometheus address and
+    // credentials.
+    Breaker BreakerOptions
+
+    // Observer, if set, is called after every request to an endpoint.
+    Observer QueryObserver
+}
+
+// QueryObserver receives the outcome of every attempt to reach a Prometheus
+// address, e.g. to export latency and error metrics. Attempts refused by an
+// open circuit breaker never reach the endpoint and are not observed.
+type QueryObserver func(address string, duration time.Duration, err error)
+
+// Pool hands out Clients keyed by their full configuration (address, auth
+// and TLS material). Clients with the same TLS material share one HTTP
+// transport, so autoscalers pointing at the same Prometheus share its
+// keep-alive connections. Clients with the same address and credentials
+// (tenant, headers, auth) share a circuit breaker, so every autoscaler backs
+// off from a failing endpoint together, while one tenant that gets errors
+// (e.g. a bad X-Scope-OrgID on a multi-tenant Mimir) does not open the
+// circuit for the others. The breakers are also the per-endpoint health used
+// for failover.
+//
+// Credentials are part of the key: when a referenced Secret changes, the
+// reconciler resolves a new Config and gets a fresh client, while the stale
+// one is dropped after ClientTTL.
+type Pool struct {
+    opts PoolOptions
+
+    mu         sync.Mutex
+    clients    map[string]*pooledClient
+    transports map[string]*http.Transport
+    breakers   map[string]*breaker
+}
+
+type pooledClient struct {
+    client       Client
+    transportKey string
+    breakerKeys  []string
+    lastUsed     time.Time
+}
+
+// NewPool returns an empty Pool.
+func NewPool(opts PoolOptions) *Pool {
+    return &Pool{
+        opts:       opts,
+        clients:    make(map[string]*pooledClient),
+        transports: make(map[string]*http.Transport),
+        breakers:   make(map[string]*breaker),
+    }
+}
+
+// Get returns the pooled client for cfg, creating it on first use.
+func (p *Pool) Get(cfg Config) (Client, error) {
+    if cfg.Timeout <= 0 {
+        cfg.Timeout = p.opts.QueryTimeout
+    }
+    key := cfg.fingerprint()
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if pc, ok := p.clients[key]; ok {
+        pc.lastUsed = time.Now()
+        return pc.client, nil
+    }
+
+    transportKey := cfg.TLS.fingerprint()
+    transport, err := p.transport(transportKey, cfg.TLS)
+    if err != nil {
+        return nil, fmt.Errorf("configuring prometheus TLS: %w", err)
+    }
+
+    addresses := append([]string{cfg.Address}, cfg.FailoverAddresses...)
+    clients := make([]Client, 0, len(addresses))
+    breakerKeys := make([]string, 0, len(addresses))
+    for _, addr := range addresses {
+        single := cfg
+        single.Address = addr
+        single.FailoverAddresses = nil
+        single.Strategy = ""
+        breakerKey := single.fingerprint()
+        breakerKeys = append(breakerKeys, breakerKey)
+        c, err := newHTTPClient(single, transport, p.opts.Retry, p.breaker(breakerKey, addr))
+        if err != nil {
+            return nil, err
+        }
+        c.observe = p.opts.Observer
+        clients = append(clients, c)
+    }
+
+    c := clients[0]
+    if len(clients) > 1 {
+        c = &failoverClient{clients: clients, strategy: cfg.Strategy}
+    }
+    p.clients[key] = &pooledClient{
+        client:       c,
+        transportKey: transportKey,
+        breakerKeys:  breakerKeys,
+        lastUsed:     time.Now(),
+    }
+    return c, nil
+}
+
+// breaker returns the shared circuit breaker for key, the fingerprint of a
+// single-address config. Callers must hold p.mu.
+func (p *Pool) breaker(key, address string) *breaker {
+    b, ok := p.breakers[key]
+    if !ok {
+        b = newBreaker(address, p.opts.Breaker)
+        p.breakers[key] = b
+    }
+    return b
+}
+
+// transport returns the shared transport for the TLS material. Callers must
+// hold p.mu.
+func (p *Pool) transport(key string, tlsCfg *TLSConfig) (*http.Transport, error) {
+    if t, ok := p.transports[key]; ok {
+        return t, nil
+    }
+
+    t, err := cloneTransport(tlsCfg)
+    if err != nil {
+        return nil, err
+    }
+    if p.opts.MaxIdleConnsPerHost > 0 {
+        t.MaxIdleConnsPerHost = p.opts.MaxIdleConnsPerHost
+    }
+    if p.opts.IdleConnTimeout > 0 {
+        t.IdleConnTimeout = p.opts.IdleConnTimeout
+    }
+    p.transports[key] = t
+    return t, nil
+}
+
+// Start periodically drops unused clients until ctx is done. It implements
+// the controller-runtime manager.Runnable interface.
+func (p *Pool) Start(ctx context.Context) error {
+    if p.opts.ClientTTL <= 0 {
+        <-ctx.Done()
+        p.close()
+        return nil
+    }
+
+    ticker := time.NewTicker(p.opts.ClientTTL / 2)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            p.close()
+            return nil
+        case now := <-ticker.C:
+            p.sweep(now)
+        }
+    }
+}
+
+// NeedLeaderElection lets the pool run on every replica; non-leaders never
+// create clients, so there is nothing to sweep there anyway.
+func (p *Pool) NeedLeaderElection() bool {
+    return false
+}
+
+// sweep removes clients idle for longer than ClientTTL, closes the
+// connections of transports no client uses any more and forgets breakers no
+// client uses any more.
+func (p *Pool) sweep(now time.Time) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    transportsInUse := make(map[string]bool, len(p.transports))
+    breakersInUse := make(map[string]bool, len(p.breakers))
+    for key, pc := range p.clients {
+        if now.Sub(pc.lastUsed) > p.opts.ClientTTL {
+            delete(p.clients, key)
+            continue
+        }
+        transportsInUse[pc.transportKey] = true
+        for _, key := range pc.breakerKeys {
+            breakersInUse[key] = true
+        }
+    }
+
+    for key, t := range p.transports {
+        if !transportsInUse[key] {
+            t.CloseIdleConnections()
+            delete(p.transports, key)
+        }
+    }
+    for key := range p.breakers {
+        if !breakersInUse[key] {
+            delete(p.breakers, key)
+        }
+    }
+}
+
+// close drops everything and closes idle connections on shutdown.
+func (p *Pool) close() {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    for _, t := range p.transports {
+        t.CloseIdleConnections()
+    }
+    p.clients = make(map[string]*pooledClient)
+    p.transports = make(map[string]*http.Transport)
+    p.breakers = make(map[string]*breaker)
+}
@@ -2,7 +2,9 @@ package metrics
 
 import (
     "context"
+    "errors"
     "fmt"
+    "net/http"
     "time"
 
     "github.com/prometheus/client_golang/api"
@@ -13,55 +15,245 @@ import (
 // Client is an interface to keep the reconciler easy to test.
 // In unit tests you can replace this with a fake implementation.
 type Client interface {
-    // QueryVector executes a PromQL expression and returns a single float64 value.
-    // We intentionally constrain ourselves to "scalar-like" queries to keep
-    // semantics simple and predictable.
-    QueryVector(ctx context.Context, promql string) (float64, error)
+    // QueryVector executes a PromQL expression and reduces the returned
+    // series to a single value according to sel.
+    QueryVector(ctx context.Context, promql string, sel Selection) (Result, error)
+
+    // QueryRange evaluates promql over a window ending now, reduces the
+    // series per timestamp according to sel and then the points to a single
+    // value according to r.
+    QueryRange(ctx context.Context, promql string, r Range, sel Selection) (Result, error)
+
+    // BuildInfo returns the version information the endpoint reports.
+    BuildInfo(ctx context.Context) (BuildInfo, error)
+}
+
+// BuildInfo is the subset of /api/v1/status/buildinfo we surface.
+type BuildInfo struct {
+    Version   string
+    Revision  string
+    Branch    string
+    GoVersion string
 }
 
 // HTTPClient implements Client using the Prometheus HTTP API.
+// Every query attempt is bounded by a timeout, transient failures are
+// retried with jittered backoff, and an optional circuit breaker shared per
+// endpoint stops sending queries to a Prometheus that keeps failing.
 type HTTPClient struct {
-    api v1.API
+    api     v1.API
+    address string
+    timeout time.Duration
+    retry   RetryOptions
+    breaker *breaker
+    observe QueryObserver
+}
+
+// NewHTTPClient builds a new Client for the given Prometheus endpoint.
+// Long-running callers should use a Pool instead, which reuses clients and
+// their connections.
+func NewHTTPClient(cfg Config) (Client, error) {
+    rt, err := newTransport(cfg.TLS)
+    if err != nil {
+        return nil, fmt.Errorf("configuring prometheus TLS: %w", err)
+    }
+    c, err := newHTTPClient(cfg, rt, DefaultRetryOptions, nil)
+    if err != nil {
+        return nil, err
+    }
+    return c, nil
 }
 
-// NewHTTPClient builds a new Client for the given Prometheus base URL.
-func NewHTTPClient(baseURL string) (Client, error) {
-    cfg := api.Config{
-        Address: baseURL,
+// newHTTPClient builds a client on top of an existing transport. b may be nil.
+func newHTTPClient(cfg Config, rt http.RoundTripper, retry RetryOptions, b *breaker) (*HTTPClient, error) {
+    // Auth sits closest to the transport so its headers override Headers.
+    if cfg.Auth != nil {
+        rt = &authRoundTripper{auth: *cfg.Auth, next: rt}
     }
+    rt = newRequestOptionsRoundTripper(cfg, rt)
 
-    c, err := api.NewClient(cfg)
+    c, err := api.NewClient(api.Config{
+        Address:      cfg.Address,
+        RoundTripper: rt,
+    })
     if err != nil {
         return nil, fmt.Errorf("creating prometheus client: %w", err)
     }
 
+    timeout := cfg.Timeout
+    if timeout <= 0 {
+        timeout = DefaultQueryTimeout
+    }
+
     return &HTTPClient{
-        api: v1.NewAPI(c),
+        api:     v1.NewAPI(c),
+        address: cfg.Address,
+        timeout: timeout,
+        retry:   retry,
+        breaker: b,
     }, nil
 }
 
-// QueryVector implements the Client interface using the v1 API.
-func (c *HTTPClient) QueryVector(ctx context.Context, promql string) (float64, error) {
-    // NOTE: In tests you may want to inject "now" for determinism.
-    result, warnings, err := c.api.Query(ctx, promql, time.Now())
-    if err != nil {
-        return 0, fmt.Errorf("prometheus query failed: %w", err)
+// do runs one API call with the per-attempt timeout, retries and breaker.
+func (c *HTTPClient) do(ctx context.Context, call func(ctx context.Context) error) error {
+    attempts := c.retry.MaxAttempts
+    if attempts < 1 {
+        attempts = 1
     }
 
-    if len(warnings) > 0 {
-        // We intentionally don't treat warnings as fatal here.
-        // They should be logged at call sites for observability.
+    var err error
+    for attempt := 0; attempt < attempts; attempt++ {
+        if attempt > 0 {
+            if sleepErr := sleep(ctx, c.retry.backoff(attempt)); sleepErr != nil {
+                return err
+            }
+        }
+        if openErr := c.breaker.allow(time.Now()); openErr != nil {
+            return openErr
+        }
+
+        start := time.Now()
+        attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+        err = call(attemptCtx)
+        cancel()
+
+        // The caller gave up (e.g. shutdown); that says nothing about the
+        // endpoint, so don't feed it to the breaker or the observer.
+        if ctx.Err() != nil {
+            c.breaker.release()
+            return err
+        }
+        if c.observe != nil {
+            c.observe(c.address, time.Since(start), err)
+        }
+        c.breaker.record(err == nil || !retryable(err), time.Now())
+        if err == nil || !retryable(err) {
+            return err
+        }
+    }
+    return err
+}
+
+// QueryVector implements the Client interface using the v1 API.
+func (c *HTTPClient) QueryVector(ctx context.Context, promql string, sel Selection) (Result, error) {
+    var (
+        result   model.Value
+        warnings v1.Warnings
+    )
+    err := c.do(ctx, func(ctx context.Context) error {
+        var err error
+        // NOTE: In tests you may want to inject "now" for determinism.
+        result, warnings, err = c.api.Query(ctx, promql, time.Now())
+        return err
+    })
+    if errors.Is(err, ErrCircuitOpen) {
+        return Result{}, err
+    }
+    if err != nil {
+        return Result{}, fmt.Errorf("prometheus query failed: %w", err)
     }
 
+    var res Result
     switch v := result.(type) {
     case model.Vector:
-        if len(v) == 0 {
-            return 0, fmt.Errorf("prometheus query returned empty vector")
-        }
-        return float64(v[0].Value), nil
+        res, err = sel.reduceVector(v)
     case *model.Scalar:
-        return float64(v.Value), nil
+        res = Result{Value: float64(v.Value), Timestamp: v.Timestamp.Time(), SeriesCount: 1}
     default:
-        return 0, fmt.Errorf("unexpected prometheus result type %T", v)
+        return Result{}, fmt.Errorf("unexpected prometheus result type %T", v)
+    }
+    res.Endpoint = c.address
+    // Warnings are not fatal here; callers log them and decide per metric.
+    res.Warnings = warnings
+    return res, err
+}
+
+// QueryRange implements the Client interface using the v1 range query API.
+func (c *HTTPClient) QueryRange(ctx context.Context, promql string, r Range, sel Selection) (Result, error) {
+    end := time.Now()
+    step := r.Step
+    if step <= 0 {
+        step = defaultRangeStep(r.Window)
+    }
+
+    var (
+        result   model.Value
+        warnings v1.Warnings
+    )
+    err := c.do(ctx, func(ctx context.Context) error {
+        var err error
+        result, warnings, err = c.api.QueryRange(ctx, promql, v1.Range{
+            Start: end.Add(-r.Window),
+            End:   end,
+            Step:  step,
+        })
+        return err
+    })
+    if errors.Is(err, ErrCircuitOpen) {
+        return Result{}, err
+    }
+    if err != nil {
+        return Result{}, fmt.Errorf("prometheus range query failed: %w", err)
+    }
+
+    m, ok := result.(model.Matrix)
+    if !ok {
+        return Result{}, fmt.Errorf("unexpected prometheus range result type %T", result)
+    }
+
+    points, count, err := sel.reduceMatrix(m)
+    res := Result{SeriesCount: count, Endpoint: c.address, Warnings: warnings}
+    if err != nil {
+        return res, err
+    }
+    if res.Value, err = reduce(points, r.Reducer); err != nil {
+        return res, err
+    }
+    res.Timestamp = points[len(points)-1].t
+    return res, nil
+}
+
+// BuildInfo implements the Client interface using the v1 API. Backends that
+// don't serve the buildinfo API (some Thanos and Cortex versions) are checked
+// with a trivial query instead and yield an empty BuildInfo.
+func (c *HTTPClient) BuildInfo(ctx context.Context) (BuildInfo, error) {
+    var info v1.BuildinfoResult
+    err := c.do(ctx, func(ctx context.Context) error {
+        var err error
+        info, err = c.api.Buildinfo(ctx)
+        return err
+    })
+    if errors.Is(err, ErrCircuitOpen) {
+        return BuildInfo{}, err
+    }
+
+    var apiErr *v1.Error
+    if errors.As(err, &apiErr) && (apiErr.Type == v1.ErrClient || apiErr.Type == v1.ErrBadResponse) {
+        err = c.do(ctx, func(ctx context.Context) error {
+            _, _, err := c.api.Query(ctx, "vector(1)", time.Now())
+            return err
+        })
+        if err == nil {
+            return BuildInfo{}, nil
+        }
+    }
+    if err != nil {
+        return BuildInfo{}, fmt.Errorf("prometheus buildinfo failed: %w", err)
+    }
+
+    return BuildInfo{
+        Version:   info.Version,
+        Revision:  info.Revision,
+        Branch:    info.Branch,
+        GoVersion: info.GoVersion,
+    }, nil
+}
+
+// defaultRangeStep picks a resolution of roughly 60 points per window.
+func defaultRangeStep(window time.Duration) time.Duration {
+    step := window / 60
+    if step < time.Second {
+        step = time.Second
     }
+    return step
 }
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "time"
+)
+
+// Reducer names how the points of a range query become a single value.
+// The names match RangeReducer in the API.
+type Reducer string
+
+const (
+    ReduceAvg   Reducer = "avg"
+    ReduceMax   Reducer = "max"
+    ReduceMin   Reducer = "min"
+    ReduceP90   Reducer = "p90"
+    ReduceP95   Reducer = "p95"
+    ReduceLast  Reducer = "last"
+    ReduceSlope Reducer = "slope"
+)
+
+// Range configures a range query ending at evaluation time.
+type Range struct {
+    Window  time.Duration
+    Step    time.Duration
+    Reducer Reducer
+}
+
+// point is one (timestamp, value) pair of a range query result.
+type point struct {
+    t time.Time
+    v float64
+}
+
+// reduce applies the reducer to points, which must be in time order.
+func reduce(points []point, r Reducer) (float64, error) {
+    if len(points) == 0 {
+        return 0, fmt.Errorf("prometheus range query returned no points")
+    }
+
+    switch r {
+    case ReduceAvg:
+        var sum float64
+        for _, p := range points {
+            sum += p.v
+        }
+        return sum / float64(len(points)), nil
+    case ReduceMax:
+        max := points[0].v
+        for _, p := range points[1:] {
+            if p.v > max {
+                max = p.v
+            }
+        }
+        return max, nil
+    case ReduceMin:
+        min := points[0].v
+        for _, p := range points[1:] {
+            if p.v < min {
+                min = p.v
+            }
+        }
+        return min, nil
+    case ReduceP90:
+        return quantile(points, 0.90), nil
+    case ReduceP95:
+        return quantile(points, 0.95), nil
+    case ReduceLast:
+        return points[len(points)-1].v, nil
+    case ReduceSlope:
+        return slope(points)
+    default:
+        return 0, fmt.Errorf("unknown range reducer %q", r)
+    }
+}
+
+// quantile interpolates linearly between the closest ranks, the same way
+// PromQL quantile_over_time does.
+func quantile(points []point, q float64) float64 {
+    values := make([]float64, len(points))
+    for i, p := range points {
+        values[i] = p.v
+    }
+    sort.Float64s(values)
+
+    rank := q * float64(len(values)-1)
+    lower := math.Floor(rank)
+    upper := math.Ceil(rank)
+    weight := rank - lower
+    return values[int(lower)]*(1-weight) + values[int(upper)]*weight
+}
+
+// slope returns the per-second slope of a least-squares fit, like deriv().
+func slope(points []point) (float64, error) {
+    if len(points) < 2 {
+        return 0, fmt.Errorf("slope needs at least two points, got %d", len(points))
+    }
+
+    // Use offsets from the first point to keep the sums numerically stable.
+    origin := points[0].t
+    var sumX, sumY, sumXY, sumXX float64
+    for _, p := range points {
+        x := p.t.Sub(origin).Seconds()
+        sumX += x
+        sumY += p.v
+        sumXY += x * p.v
+        sumXX += x * x
+    }
+
+    n := float64(len(points))
+    den := n*sumXX - sumX*sumX
+    if den == 0 {
+        return 0, nil
+    }
+    return (n*sumXY - sumX*sumY) / den, nil
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+    "math"
+    "testing"
+    "time"
+)
+
+// pointsEvery returns points spaced step apart, starting at the zero time.
+func pointsEvery(step time.Duration, values ...float64) []point {
+    start := time.Unix(0, 0)
+    points := make([]point, len(values))
+    for i, v := range values {
+        points[i] = point{t: start.Add(time.Duration(i) * step), v: v}
+    }
+    return points
+}
+
+func TestReduce(t *testing.T) {
+    ten := pointsEvery(time.Second, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+    tests := []struct {
+        name    string
+        points  []point
+        reducer Reducer
+        want    float64
+        wantErr bool
+    }{
+        {name: "avg", points: ten, reducer: ReduceAvg, want: 5.5},
+        {name: "max", points: ten, reducer: ReduceMax, want: 10},
+        {name: "min", points: ten, reducer: ReduceMin, want: 1},
+        {name: "last", points: ten, reducer: ReduceLast, want: 10},
+        {name: "p90 interpolates", points: ten, reducer: ReduceP90, want: 9.1},
+        {name: "p95 interpolates", points: ten, reducer: ReduceP95, want: 9.55},
+        {name: "quantile ignores order", points: pointsEvery(time.Second, 10, 1, 5), reducer: ReduceP90, want: 9},
+        {name: "quantile of one point", points: pointsEvery(time.Second, 7), reducer: ReduceP95, want: 7},
+        {name: "slope per second", points: pointsEvery(10*time.Second, 0, 5, 10, 15), reducer: ReduceSlope, want: 0.5},
+        {name: "slope of a flat line", points: pointsEvery(time.Second, 3, 3, 3), reducer: ReduceSlope, want: 0},
+        {name: "negative slope", points: pointsEvery(time.Second, 4, 2, 0), reducer: ReduceSlope, want: -2},
+        {name: "slope needs two points", points: pointsEvery(time.Second, 1), reducer: ReduceSlope, wantErr: true},
+        {name: "no points", points: nil, reducer: ReduceAvg, wantErr: true},
+        {name: "unknown reducer", points: ten, reducer: "median", wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := reduce(tt.points, tt.reducer)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("reduce error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if !tt.wantErr && math.Abs(got-tt.want) > 1e-9 {
+                t.Errorf("reduce = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
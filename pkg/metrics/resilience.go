@@ -0,0 +1,162 @@
+package metrics
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "net"
+    "sync"
+    "time"
+
+    v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// DefaultQueryTimeout bounds a single query attempt when none is configured.
+const DefaultQueryTimeout = 10 * time.Second
+
+// ErrCircuitOpen is returned without contacting Prometheus while the
+// endpoint's circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// RetryOptions bounds retries of transient failures (5xx, timeouts and
+// connection errors).
+type RetryOptions struct {
+    // MaxAttempts is the total number of tries per query, including the first.
+    MaxAttempts int
+
+    // BaseBackoff is doubled per retry up to MaxBackoff; the actual sleep is
+    // a random duration up to that value ("full jitter").
+    BaseBackoff time.Duration
+    MaxBackoff  time.Duration
+}
+
+// DefaultRetryOptions is used by standalone clients.
+var DefaultRetryOptions = RetryOptions{
+    MaxAttempts: 3,
+    BaseBackoff: 200 * time.Millisecond,
+    MaxBackoff:  2 * time.Second,
+}
+
+// backoff returns the jittered sleep before the given retry (1-based).
+func (o RetryOptions) backoff(retry int) time.Duration {
+    d := o.BaseBackoff
+    for i := 1; i < retry && d < o.MaxBackoff; i++ {
+        d *= 2
+    }
+    if o.MaxBackoff > 0 && d > o.MaxBackoff {
+        d = o.MaxBackoff
+    }
+    if d <= 0 {
+        return 0
+    }
+    return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// BreakerOptions configures the per-endpoint circuit breaker.
+type BreakerOptions struct {
+    // FailureThreshold consecutive failed queries open the breaker.
+    // Zero disables the breaker.
+    FailureThreshold int
+
+    // OpenDuration is how long the breaker stays open before a single probe
+    // query is let through (half-open).
+    OpenDuration time.Duration
+}
+
+// breaker is a small consecutive-failures circuit breaker shared by every
+// client talking to the same Prometheus address.
+type breaker struct {
+    address string
+    opts    BreakerOptions
+
+    mu        sync.Mutex
+    failures  int
+    openUntil time.Time
+    probing   bool
+}
+
+func newBreaker(address string, opts BreakerOptions) *breaker {
+    return &breaker{address: address, opts: opts}
+}
+
+// allow returns an ErrCircuitOpen error when the request must not be sent.
+// Once the open period is over, exactly one probe is let through.
+func (b *breaker) allow(now time.Time) error {
+    if b == nil || b.opts.FailureThreshold <= 0 {
+        return nil
+    }
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if b.failures < b.opts.FailureThreshold {
+        return nil
+    }
+    if now.Before(b.openUntil) {
+        return fmt.Errorf("%w for %s after %d consecutive failures (open until %s)",
+            ErrCircuitOpen, b.address, b.failures, b.openUntil.UTC().Format(time.RFC3339))
+    }
+    if b.probing {
+        return fmt.Errorf("%w for %s after %d consecutive failures (half-open, probe in flight)",
+            ErrCircuitOpen, b.address, b.failures)
+    }
+    b.probing = true
+    return nil
+}
+
+// record updates the breaker with the outcome of a request it allowed.
+func (b *breaker) record(healthy bool, now time.Time) {
+    if b == nil || b.opts.FailureThreshold <= 0 {
+        return
+    }
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.probing = false
+    if healthy {
+        b.failures = 0
+        return
+    }
+    b.failures++
+    if b.failures >= b.opts.FailureThreshold {
+        b.openUntil = now.Add(b.opts.OpenDuration)
+    }
+}
+
+// release ends a request the breaker allowed without recording an outcome,
+// so a cancelled half-open probe lets the next one through.
+func (b *breaker) release() {
+    if b == nil || b.opts.FailureThreshold <= 0 {
+        return
+    }
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.probing = false
+}
+
+// retryable reports whether err says the endpoint itself is unhealthy, as
+// opposed to a problem with the query (bad PromQL, execution errors).
+func retryable(err error) bool {
+    var apiErr *v1.Error
+    if errors.As(err, &apiErr) {
+        return apiErr.Type == v1.ErrServer || apiErr.Type == v1.ErrTimeout
+    }
+    var netErr net.Error
+    return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+    t := time.NewTimer(d)
+    defer t.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-t.C:
+        return nil
+    }
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net"
+    "testing"
+    "time"
+
+    v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+func TestBreaker(t *testing.T) {
+    now := time.Now()
+    b := newBreaker("http://prometheus:9090", BreakerOptions{FailureThreshold: 2, OpenDuration: time.Minute})
+
+    mustAllow := func(at time.Time) {
+        t.Helper()
+        if err := b.allow(at); err != nil {
+            t.Fatalf("allow: %v", err)
+        }
+    }
+    mustRefuse := func(at time.Time) {
+        t.Helper()
+        if err := b.allow(at); !errors.Is(err, ErrCircuitOpen) {
+            t.Fatalf("allow = %v, want ErrCircuitOpen", err)
+        }
+    }
+
+    // Closed: failures below the threshold don't open it.
+    mustAllow(now)
+    b.record(false, now)
+    mustAllow(now)
+
+    // Open after the threshold is reached.
+    b.record(false, now)
+    mustRefuse(now.Add(30 * time.Second))
+
+    // Half-open: exactly one probe goes through.
+    probeAt := now.Add(time.Minute)
+    mustAllow(probeAt)
+    mustRefuse(probeAt)
+
+    // A failed probe opens the circuit again.
+    b.record(false, probeAt)
+    mustRefuse(probeAt.Add(30 * time.Second))
+
+    // A released probe (caller gave up) lets the next one through.
+    probeAt = probeAt.Add(time.Minute)
+    mustAllow(probeAt)
+    b.release()
+    mustAllow(probeAt)
+
+    // A healthy probe closes the circuit.
+    b.record(true, probeAt)
+    mustAllow(probeAt)
+    mustAllow(probeAt)
+}
+
+func TestBreakerDisabled(t *testing.T) {
+    b := newBreaker("http://prometheus:9090", BreakerOptions{})
+    for i := 0; i < 10; i++ {
+        b.record(false, time.Now())
+    }
+    if err := b.allow(time.Now()); err != nil {
+        t.Errorf("disabled breaker refused a request: %v", err)
+    }
+
+    var nilBreaker *breaker
+    if err := nilBreaker.allow(time.Now()); err != nil {
+        t.Errorf("nil breaker refused a request: %v", err)
+    }
+}
+
+func TestIsEndpointFailure(t *testing.T) {
+    tests := []struct {
+        name string
+        err  error
+        want bool
+    }{
+        {"server error", &v1.Error{Type: v1.ErrServer}, true},
+        {"timeout", &v1.Error{Type: v1.ErrTimeout}, true},
+        {"bad query", &v1.Error{Type: v1.ErrBadData}, false},
+        {"execution error", &v1.Error{Type: v1.ErrExec}, false},
+        {"deadline exceeded", fmt.Errorf("query: %w", context.DeadlineExceeded), true},
+        {"connection refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+        {"circuit open", fmt.Errorf("%w for http://prometheus:9090", ErrCircuitOpen), true},
+        {"selection error", errors.New("prometheus query returned 3 series"), false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := IsEndpointFailure(tt.err); got != tt.want {
+                t.Errorf("IsEndpointFailure(%v) = %v, want %v", tt.err, got, tt.want)
+            }
+        })
+    }
+}
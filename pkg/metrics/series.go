@@ -0,0 +1,196 @@
+package metrics
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/prometheus/common/model"
+)
+
+// SeriesReduce names how several series become a single value.
+// The names match SeriesReduce in the API.
+type SeriesReduce string
+
+const (
+    SeriesSum   SeriesReduce = "sum"
+    SeriesMax   SeriesReduce = "max"
+    SeriesMin   SeriesReduce = "min"
+    SeriesAvg   SeriesReduce = "avg"
+    SeriesCount SeriesReduce = "count"
+    SeriesError SeriesReduce = "error"
+)
+
+// Selection picks and combines the series returned by a query.
+type Selection struct {
+    // Match keeps only series whose labels equal every entry.
+    Match map[string]string
+
+    // Reduce combines the matched series. Empty means SeriesError.
+    Reduce SeriesReduce
+}
+
+// Result is a query reduced to a single value.
+type Result struct {
+    Value float64
+
+    // Timestamp is the time of the returned sample. Prometheus stamps
+    // instant query samples with the evaluation time; for range queries it
+    // is the newest point in the window, so it shows how stale the data is.
+    // Zero when unknown.
+    Timestamp time.Time
+
+    // SeriesCount is how many series Prometheus returned, before Match.
+    // It is set even when the query fails because of the selection.
+    SeriesCount int
+
+    // Endpoint is the address that answered the query.
+    Endpoint string
+
+    // Warnings are returned by Prometheus alongside the data, e.g. partial
+    // responses from Thanos or Mimir.
+    Warnings []string
+}
+
+// PartialResponse returns the warnings that report missing data. Prometheus
+// prefixes PromQL annotations with "PromQL info:" or "PromQL warning:"; any
+// other warning comes from the storage layer, e.g. a Thanos store or Mimir
+// ingester that did not answer.
+func (r Result) PartialResponse() []string {
+    var partial []string
+    for _, w := range r.Warnings {
+        if strings.HasPrefix(w, "PromQL info:") || strings.HasPrefix(w, "PromQL warning:") {
+            continue
+        }
+        partial = append(partial, w)
+    }
+    return partial
+}
+
+// matches reports whether the series labels satisfy Match.
+func (s Selection) matches(m model.Metric) bool {
+    for k, v := range s.Match {
+        if string(m[model.LabelName(k)]) != v {
+            return false
+        }
+    }
+    return true
+}
+
+// check validates how many series are left after Match.
+func (s Selection) check(matched, total int) error {
+    if matched == 0 && s.Reduce != SeriesCount {
+        if total > 0 {
+            return fmt.Errorf("none of the %d series matched the series selector", total)
+        }
+        return fmt.Errorf("prometheus query returned no series")
+    }
+    if matched > 1 && (s.Reduce == "" || s.Reduce == SeriesError) {
+        return fmt.Errorf("prometheus query returned %d series; aggregate it in PromQL or set seriesReduce", matched)
+    }
+    return nil
+}
+
+// combine reduces one value per series to a single value.
+func (s Selection) combine(values []float64) (float64, error) {
+    if s.Reduce == SeriesCount {
+        return float64(len(values)), nil
+    }
+    if len(values) == 0 {
+        return 0, fmt.Errorf("no series to reduce")
+    }
+
+    switch s.Reduce {
+    case "", SeriesError:
+        return values[0], nil
+    case SeriesSum, SeriesAvg:
+        var sum float64
+        for _, v := range values {
+            sum += v
+        }
+        if s.Reduce == SeriesAvg {
+            return sum / float64(len(values)), nil
+        }
+        return sum, nil
+    case SeriesMax:
+        max := values[0]
+        for _, v := range values[1:] {
+            if v > max {
+                max = v
+            }
+        }
+        return max, nil
+    case SeriesMin:
+        min := values[0]
+        for _, v := range values[1:] {
+            if v < min {
+                min = v
+            }
+        }
+        return min, nil
+    default:
+        return 0, fmt.Errorf("unknown series reducer %q", s.Reduce)
+    }
+}
+
+// reduceVector applies the selection to an instant query result.
+func (s Selection) reduceVector(v model.Vector) (Result, error) {
+    res := Result{SeriesCount: len(v)}
+
+    values := make([]float64, 0, len(v))
+    for _, smp := range v {
+        if !s.matches(smp.Metric) {
+            continue
+        }
+        values = append(values, float64(smp.Value))
+        if ts := smp.Timestamp.Time(); res.Timestamp.IsZero() || ts.Before(res.Timestamp) {
+            res.Timestamp = ts
+        }
+    }
+    if err := s.check(len(values), len(v)); err != nil {
+        return res, err
+    }
+
+    val, err := s.combine(values)
+    if err != nil {
+        return res, err
+    }
+    res.Value = val
+    return res, nil
+}
+
+// reduceMatrix applies the selection to a range query result, combining the
+// series per timestamp so the window reducer sees a single series.
+func (s Selection) reduceMatrix(m model.Matrix) ([]point, int, error) {
+    byTime := make(map[model.Time][]float64)
+    matched := 0
+    for _, stream := range m {
+        if !s.matches(stream.Metric) {
+            continue
+        }
+        matched++
+        for _, sp := range stream.Values {
+            byTime[sp.Timestamp] = append(byTime[sp.Timestamp], float64(sp.Value))
+        }
+    }
+    if err := s.check(matched, len(m)); err != nil {
+        return nil, len(m), err
+    }
+
+    times := make([]model.Time, 0, len(byTime))
+    for t := range byTime {
+        times = append(times, t)
+    }
+    sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+    points := make([]point, 0, len(times))
+    for _, t := range times {
+        v, err := s.combine(byTime[t])
+        if err != nil {
+            return nil, len(m), err
+        }
+        points = append(points, point{t: t.Time(), v: v})
+    }
+    return points, len(m), nil
+}
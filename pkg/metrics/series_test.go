@@ -0,0 +1,112 @@
+package metrics
+
+import (
+    "reflect"
+    "testing"
+
+    "github.com/prometheus/common/model"
+)
+
+func sample(pod string, v float64) *model.Sample {
+    return &model.Sample{
+        Metric:    model.Metric{"pod": model.LabelValue(pod)},
+        Value:     model.SampleValue(v),
+        Timestamp: model.Time(1000),
+    }
+}
+
+func TestSelectionReduceVector(t *testing.T) {
+    threeSeries := model.Vector{sample("a", 1), sample("b", 4), sample("c", 7)}
+
+    tests := []struct {
+        name      string
+        sel       Selection
+        vector    model.Vector
+        want      float64
+        wantErr   bool
+        wantCount int
+    }{
+        {name: "single series", vector: model.Vector{sample("a", 3)}, want: 3, wantCount: 1},
+        {name: "several series fail by default", vector: threeSeries, wantErr: true, wantCount: 3},
+        {name: "explicit error", sel: Selection{Reduce: SeriesError}, vector: threeSeries, wantErr: true, wantCount: 3},
+        {name: "sum", sel: Selection{Reduce: SeriesSum}, vector: threeSeries, want: 12, wantCount: 3},
+        {name: "avg", sel: Selection{Reduce: SeriesAvg}, vector: threeSeries, want: 4, wantCount: 3},
+        {name: "max", sel: Selection{Reduce: SeriesMax}, vector: threeSeries, want: 7, wantCount: 3},
+        {name: "min", sel: Selection{Reduce: SeriesMin}, vector: threeSeries, want: 1, wantCount: 3},
+        {name: "count", sel: Selection{Reduce: SeriesCount}, vector: threeSeries, want: 3, wantCount: 3},
+        {name: "count of nothing", sel: Selection{Reduce: SeriesCount}, vector: nil, want: 0},
+        {
+            name:      "match picks one series",
+            sel:       Selection{Match: map[string]string{"pod": "b"}},
+            vector:    threeSeries,
+            want:      4,
+            wantCount: 3,
+        },
+        {
+            name:      "match without hits",
+            sel:       Selection{Match: map[string]string{"pod": "z"}, Reduce: SeriesSum},
+            vector:    threeSeries,
+            wantErr:   true,
+            wantCount: 3,
+        },
+        {name: "no series", vector: nil, wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            res, err := tt.sel.reduceVector(tt.vector)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("reduceVector error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if res.SeriesCount != tt.wantCount {
+                t.Errorf("SeriesCount = %d, want %d", res.SeriesCount, tt.wantCount)
+            }
+            if !tt.wantErr && res.Value != tt.want {
+                t.Errorf("Value = %v, want %v", res.Value, tt.want)
+            }
+        })
+    }
+}
+
+func TestSelectionReduceMatrix(t *testing.T) {
+    stream := func(pod string, values ...float64) *model.SampleStream {
+        s := &model.SampleStream{Metric: model.Metric{"pod": model.LabelValue(pod)}}
+        for i, v := range values {
+            s.Values = append(s.Values, model.SamplePair{Timestamp: model.Time(i * 1000), Value: model.SampleValue(v)})
+        }
+        return s
+    }
+
+    // Series are combined per timestamp before the window reducer runs.
+    m := model.Matrix{stream("a", 1, 2, 3), stream("b", 10, 20)}
+    points, count, err := Selection{Reduce: SeriesSum}.reduceMatrix(m)
+    if err != nil {
+        t.Fatalf("reduceMatrix: %v", err)
+    }
+    if count != 2 {
+        t.Errorf("series count = %d, want 2", count)
+    }
+    var got []float64
+    for _, p := range points {
+        got = append(got, p.v)
+    }
+    if want := []float64{11, 22, 3}; !reflect.DeepEqual(got, want) {
+        t.Errorf("points = %v, want %v", got, want)
+    }
+
+    if _, _, err := (Selection{}).reduceMatrix(m); err == nil {
+        t.Error("reduceMatrix accepted several series without seriesReduce")
+    }
+}
+
+func TestPartialResponse(t *testing.T) {
+    res := Result{Warnings: []string{
+        "PromQL info: metric might not be a counter",
+        "store gateway 10.0.0.1 did not respond",
+        "PromQL warning: encountered a mix of histograms",
+    }}
+    want := []string{"store gateway 10.0.0.1 did not respond"}
+    if got := res.PartialResponse(); !reflect.DeepEqual(got, want) {
+        t.Errorf("PartialResponse = %v, want %v", got, want)
+    }
+}
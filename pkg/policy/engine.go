@@ -2,11 +2,16 @@ package policy
 
 import (
     "fmt"
+    "math"
     "time"
 
     autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
 )
 
+// DefaultTargetTolerance is the relative band around a metric target inside
+// which we keep the current replica count (same default as the HPA).
+const DefaultTargetTolerance = 0.1
+
 // Input is what the reconciler passes into the engine for a single decision.
 type Input struct {
     CurrentReplicas int32
@@ -15,6 +20,11 @@ type Input struct {
     // Samples maps metric name -> last Prometheus value.
     Samples map[string]float64
 
+    // Failures maps metric name -> query error for metrics that failed in
+    // this evaluation. A failed metric may still have a sample when the
+    // caller substituted its last known value.
+    Failures map[string]string
+
     // Meta information for behavior tuning.
     Now           time.Time
     LastScaleTime *time.Time
@@ -34,6 +44,17 @@ type Decision struct {
     DesiredReplicas int32
     Reason          string
     CooldownActive  bool
+
+    // MetricDesired holds the replicas each metric with a sample voted for,
+    // before aggregation, bounds and cooldowns.
+    MetricDesired map[string]int32
+
+    // Vetoes lists the gate metrics that were tripped in this decision.
+    Vetoes []string
+
+    // HoldReason is set when metric failures forced us to keep the current
+    // replica count.
+    HoldReason string
 }
 
 // Engine defines the contract; keeping it as an interface allows easy testing
@@ -55,25 +76,76 @@ func (e *DefaultEngine) Decide(in Input) (Decision, error) {
     if len(in.Spec.Metrics) == 0 {
         return Decision{}, fmt.Errorf("no metrics defined in spec")
     }
+    // A gate without its threshold would silently never veto.
+    for _, ms := range in.Spec.Metrics {
+        if ms.Role == autoscalerv1alpha1.MetricRoleGate && ms.Gate == nil {
+            return Decision{}, fmt.Errorf("metric %q has role gate but no gate configured", ms.Name)
+        }
+    }
 
     desired := in.CurrentReplicas
     reasons := []string{}
 
     var metricDesired []int32
     var metricWeights []float64
+    votes := make(map[string]int32, len(in.Spec.Metrics))
+
+    var vetoes []string
+    holdByGate := false
+
+    var holdReasons []string
+    healthy := 0
 
     for _, ms := range in.Spec.Metrics {
         sample, ok := in.Samples[ms.Name]
+
+        queryErr, failed := in.Failures[ms.Name]
+        if !failed {
+            healthy++
+        }
+
+        // Without a sample, the metric's onError policy decides what it does.
+        var onError autoscalerv1alpha1.MetricErrorAction
+        if !ok && failed {
+            onError = errorAction(ms)
+            reasons = append(reasons, fmt.Sprintf("%s=error (%s): %s", ms.Name, onError, queryErr))
+            if onError == autoscalerv1alpha1.MetricErrorHold {
+                holdReasons = append(holdReasons, ms.Name+" failed with onError=Hold")
+            }
+        }
+
+        if ms.Role == autoscalerv1alpha1.MetricRoleGate {
+            // Gates never vote; a missing gate sample is neutral like any other.
+            if !ok || sample <= ms.Gate.Threshold {
+                continue
+            }
+            action := ms.Gate.Action
+            if action == "" {
+                action = autoscalerv1alpha1.GateActionBlockScaleUp
+            }
+            if action == autoscalerv1alpha1.GateActionHold {
+                holdByGate = true
+            }
+            vetoes = append(vetoes, fmt.Sprintf("%s=%.4f>%g (%s)", ms.Name, sample, ms.Gate.Threshold, action))
+            continue
+        }
+
         if !ok {
-            // Missing metric is treated as neutral. We log this at the call site
-            // instead of failing the entire reconciliation.
-            metricDesired = append(metricDesired, desired)
+            // Missing metric is treated as neutral unless onError asks for
+            // maxReplicas. We log this at the call site instead of failing
+            // the entire reconciliation.
+            vote := desired
+            if onError == autoscalerv1alpha1.MetricErrorScaleToMax {
+                vote = in.Spec.MaxReplicas
+            }
+            metricDesired = append(metricDesired, vote)
             metricWeights = append(metricWeights, 1.0)
             continue
         }
 
         perMetricDesired := e.desiredFromMetric(in.CurrentReplicas, sample, ms)
         metricDesired = append(metricDesired, perMetricDesired)
+        votes[ms.Name] = perMetricDesired
 
         weight := 1.0
         if ms.Weight != nil {
@@ -84,8 +156,28 @@ func (e *DefaultEngine) Decide(in Input) (Decision, error) {
         reasons = append(reasons, fmt.Sprintf("%s=%.4f -> %d", ms.Name, sample, perMetricDesired))
     }
 
-    aggregated := e.aggregate(metricDesired, metricWeights, in.Spec.Aggregation)
-    desired = aggregated
+    // With only gate metrics there is nothing to aggregate; stay where we are.
+    if len(metricDesired) > 0 {
+        desired = e.aggregate(metricDesired, metricWeights, in.Spec.Aggregation)
+    }
+
+    // Tripped gates override whatever the aggregation decided. Hard bounds
+    // below still win so a gate never keeps us outside min/max.
+    if len(vetoes) > 0 {
+        if holdByGate || desired > in.CurrentReplicas {
+            desired = in.CurrentReplicas
+        }
+    }
+
+    // Too few healthy metrics, or a failed metric with onError=Hold, means we
+    // do not trust this evaluation enough to act on it.
+    if min := in.Spec.MinHealthyMetrics; min != nil && int32(healthy) < *min {
+        holdReasons = append(holdReasons, fmt.Sprintf("%d healthy metrics, %d required", healthy, *min))
+    }
+    holdReason := joinReasons(holdReasons)
+    if holdReason != "" {
+        desired = in.CurrentReplicas
+    }
 
     // Respect hard min/max bounds from spec.
     if desired < in.Spec.MinReplicas {
@@ -99,16 +191,37 @@ func (e *DefaultEngine) Decide(in Input) (Decision, error) {
     desired = cooled
 
     reason := fmt.Sprintf("metrics=[%s], aggregation=%s", joinReasons(reasons), in.Spec.Aggregation)
+    if len(vetoes) > 0 {
+        reason += fmt.Sprintf(", vetoed=[%s]", joinReasons(vetoes))
+    }
+    if holdReason != "" {
+        reason += fmt.Sprintf(", held=[%s]", holdReason)
+    }
 
     return Decision{
         DesiredReplicas: desired,
         Reason:          reason,
         CooldownActive:  cooldownActive,
+        MetricDesired:   votes,
+        Vetoes:          vetoes,
+        HoldReason:      holdReason,
     }, nil
 }
 
+// errorAction returns the configured onError action, defaulting to Skip.
+func errorAction(ms autoscalerv1alpha1.MetricSpec) autoscalerv1alpha1.MetricErrorAction {
+    if ms.OnError == nil || ms.OnError.Action == "" {
+        return autoscalerv1alpha1.MetricErrorSkip
+    }
+    return ms.OnError.Action
+}
+
 // desiredFromMetric maps one metric sample to a desired replica count.
 func (e *DefaultEngine) desiredFromMetric(current int32, sample float64, ms autoscalerv1alpha1.MetricSpec) int32 {
+    if ms.Target != nil {
+        return e.desiredFromTarget(current, sample, *ms.Target)
+    }
+
     desired := current
 
     // Scale up if configured and sample is above the threshold.
@@ -127,6 +240,49 @@ func (e *DefaultEngine) desiredFromMetric(current int32, sample float64, ms auto
     return desired
 }
 
+// desiredFromTarget implements proportional scaling: desired replicas are
+// ceil(current * sample / target), unless the ratio stays within tolerance.
+func (e *DefaultEngine) desiredFromTarget(current int32, sample float64, target autoscalerv1alpha1.MetricTarget) int32 {
+    if target.Value <= 0 {
+        return current
+    }
+
+    tolerance := DefaultTargetTolerance
+    if target.Tolerance != nil {
+        tolerance = *target.Tolerance
+    }
+
+    // A workload scaled to zero still needs a base to multiply from.
+    base := float64(current)
+    if base < 1 {
+        base = 1
+    }
+
+    var ratio, desired float64
+    switch target.Type {
+    case autoscalerv1alpha1.MetricTargetAverageValue:
+        // The sample is a total, so the target is what one replica should handle.
+        ratio = sample / (target.Value * base)
+        desired = math.Ceil(sample / target.Value)
+    default:
+        // Value and Utilization both compare the sample with the target directly.
+        ratio = sample / target.Value
+        desired = math.Ceil(base * ratio)
+    }
+
+    if math.IsNaN(ratio) || math.IsInf(ratio, 0) || math.Abs(ratio-1.0) <= tolerance {
+        return current
+    }
+
+    if desired < 1 {
+        desired = 1
+    }
+    if desired > math.MaxInt32 {
+        desired = math.MaxInt32
+    }
+    return int32(desired)
+}
+
 // aggregate combines the per-metric recommendations into a single number.
 func (e *DefaultEngine) aggregate(desired []int32, weights []float64, strategy autoscalerv1alpha1.AggregationStrategy) int32 {
     if len(desired) == 0 {
@@ -0,0 +1,312 @@
+package policy
+
+import (
+    "math"
+    "testing"
+    "time"
+
+    autoscalerv1alpha1 "github.com/MreliotA/prometheus-policy-autoscaler/api/v1alpha1"
+)
+
+func float64Ptr(v float64) *float64 { return &v }
+func int32Ptr(v int32) *int32       { return &v }
+
+func TestDesiredFromTarget(t *testing.T) {
+    value := func(v float64) autoscalerv1alpha1.MetricTarget {
+        return autoscalerv1alpha1.MetricTarget{Type: autoscalerv1alpha1.MetricTargetValue, Value: v}
+    }
+    average := func(v float64) autoscalerv1alpha1.MetricTarget {
+        return autoscalerv1alpha1.MetricTarget{Type: autoscalerv1alpha1.MetricTargetAverageValue, Value: v}
+    }
+    withTolerance := func(target autoscalerv1alpha1.MetricTarget, tol float64) autoscalerv1alpha1.MetricTarget {
+        target.Tolerance = &tol
+        return target
+    }
+
+    tests := []struct {
+        name    string
+        current int32
+        sample  float64
+        target  autoscalerv1alpha1.MetricTarget
+        want    int32
+    }{
+        {"value above target", 4, 200, value(100), 8},
+        {"value below target", 4, 50, value(100), 2},
+        {"value rounds up", 4, 105, withTolerance(value(100), 0), 5},
+        {"within default tolerance", 4, 105, value(100), 4},
+        {"within custom tolerance", 4, 125, withTolerance(value(100), 0.3), 4},
+        {"average value uses the total", 4, 95, average(10), 10},
+        {"average value within tolerance", 4, 41, average(10), 4},
+        {"scaled to zero still scales up", 0, 300, value(100), 3},
+        {"zero sample keeps one replica", 4, 0, value(100), 1},
+        {"NaN sample keeps current", 4, math.NaN(), value(100), 4},
+        {"infinite sample keeps current", 4, math.Inf(1), value(100), 4},
+        {"non-positive target keeps current", 4, 200, value(0), 4},
+    }
+
+    e := &DefaultEngine{}
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := e.desiredFromTarget(tt.current, tt.sample, tt.target); got != tt.want {
+                t.Errorf("desiredFromTarget(%d, %v) = %d, want %d", tt.current, tt.sample, got, tt.want)
+            }
+        })
+    }
+}
+
+// rpsMetric scales up by 2 above 100 and down by 1 below 10.
+func rpsMetric() autoscalerv1alpha1.MetricSpec {
+    return autoscalerv1alpha1.MetricSpec{
+        Name:      "rps",
+        ScaleUp:   &autoscalerv1alpha1.ScaleDirection{Threshold: 100, Step: 2},
+        ScaleDown: &autoscalerv1alpha1.ScaleDirection{Threshold: 10, Step: 1},
+    }
+}
+
+// queueMetric scales up by 4 above 10.
+func queueMetric(onError autoscalerv1alpha1.MetricErrorAction) autoscalerv1alpha1.MetricSpec {
+    ms := autoscalerv1alpha1.MetricSpec{
+        Name:    "queue",
+        ScaleUp: &autoscalerv1alpha1.ScaleDirection{Threshold: 10, Step: 4},
+    }
+    if onError != "" {
+        ms.OnError = &autoscalerv1alpha1.MetricErrorPolicy{Action: onError}
+    }
+    return ms
+}
+
+func gateMetric(action autoscalerv1alpha1.GateAction) autoscalerv1alpha1.MetricSpec {
+    return autoscalerv1alpha1.MetricSpec{
+        Name: "errors",
+        Role: autoscalerv1alpha1.MetricRoleGate,
+        Gate: &autoscalerv1alpha1.GateSpec{Threshold: 0.05, Action: action},
+    }
+}
+
+func specWith(metrics ...autoscalerv1alpha1.MetricSpec) autoscalerv1alpha1.PrometheusAutoscalerSpec {
+    return autoscalerv1alpha1.PrometheusAutoscalerSpec{
+        MinReplicas: 1,
+        MaxReplicas: 10,
+        Aggregation: autoscalerv1alpha1.AggregationMax,
+        Metrics:     metrics,
+    }
+}
+
+func TestDecide(t *testing.T) {
+    tests := []struct {
+        name       string
+        spec       autoscalerv1alpha1.PrometheusAutoscalerSpec
+        samples    map[string]float64
+        failures   map[string]string
+        want       int32
+        wantVetoes int
+        wantHold   bool
+    }{
+        {
+            name:    "gate below threshold",
+            spec:    specWith(rpsMetric(), gateMetric("")),
+            samples: map[string]float64{"rps": 150, "errors": 0.01},
+            want:    5,
+        },
+        {
+            name:       "gate blocks scale up",
+            spec:       specWith(rpsMetric(), gateMetric("")),
+            samples:    map[string]float64{"rps": 150, "errors": 0.1},
+            want:       3,
+            wantVetoes: 1,
+        },
+        {
+            name:       "blocking gate allows scale down",
+            spec:       specWith(rpsMetric(), gateMetric(autoscalerv1alpha1.GateActionBlockScaleUp)),
+            samples:    map[string]float64{"rps": 5, "errors": 0.1},
+            want:       2,
+            wantVetoes: 1,
+        },
+        {
+            name:       "hold gate blocks scale down",
+            spec:       specWith(rpsMetric(), gateMetric(autoscalerv1alpha1.GateActionHold)),
+            samples:    map[string]float64{"rps": 5, "errors": 0.1},
+            want:       3,
+            wantVetoes: 1,
+        },
+        {
+            name:     "failed gate is neutral",
+            spec:     specWith(rpsMetric(), gateMetric("")),
+            samples:  map[string]float64{"rps": 150},
+            failures: map[string]string{"errors": "timeout"},
+            want:     5,
+        },
+        {
+            name:     "onError skip is neutral",
+            spec:     specWith(rpsMetric(), queueMetric("")),
+            samples:  map[string]float64{"rps": 150},
+            failures: map[string]string{"queue": "timeout"},
+            want:     5,
+        },
+        {
+            name:     "onError scale to max",
+            spec:     specWith(rpsMetric(), queueMetric(autoscalerv1alpha1.MetricErrorScaleToMax)),
+            samples:  map[string]float64{"rps": 150},
+            failures: map[string]string{"queue": "timeout"},
+            want:     10,
+        },
+        {
+            name:     "onError hold",
+            spec:     specWith(rpsMetric(), queueMetric(autoscalerv1alpha1.MetricErrorHold)),
+            samples:  map[string]float64{"rps": 150},
+            failures: map[string]string{"queue": "timeout"},
+            want:     3,
+            wantHold: true,
+        },
+        {
+            name:     "last known value still votes",
+            spec:     specWith(rpsMetric(), queueMetric(autoscalerv1alpha1.MetricErrorUseLastKnown)),
+            samples:  map[string]float64{"rps": 150, "queue": 20},
+            failures: map[string]string{"queue": "timeout"},
+            want:     7,
+        },
+        {
+            name: "too few healthy metrics",
+            spec: func() autoscalerv1alpha1.PrometheusAutoscalerSpec {
+                s := specWith(rpsMetric(), queueMetric(""))
+                s.MinHealthyMetrics = int32Ptr(2)
+                return s
+            }(),
+            samples:  map[string]float64{"rps": 150},
+            failures: map[string]string{"queue": "timeout"},
+            want:     3,
+            wantHold: true,
+        },
+        {
+            name: "weighted aggregation",
+            spec: func() autoscalerv1alpha1.PrometheusAutoscalerSpec {
+                rps, queue := rpsMetric(), queueMetric("")
+                rps.Weight = float64Ptr(3)
+                queue.Weight = float64Ptr(1)
+                s := specWith(rps, queue)
+                s.Aggregation = autoscalerv1alpha1.AggregationWeighted
+                return s
+            }(),
+            samples: map[string]float64{"rps": 5, "queue": 20},
+            want:    3, // (2*3 + 7*1) / 4
+        },
+        {
+            name: "bounded by max replicas",
+            spec: func() autoscalerv1alpha1.PrometheusAutoscalerSpec {
+                s := specWith(queueMetric(""))
+                s.MaxReplicas = 5
+                return s
+            }(),
+            samples: map[string]float64{"queue": 20},
+            want:    5,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            d, err := NewEngine().Decide(Input{
+                CurrentReplicas: 3,
+                Spec:            tt.spec,
+                Samples:         tt.samples,
+                Failures:        tt.failures,
+                Now:             time.Now(),
+            })
+            if err != nil {
+                t.Fatalf("Decide: %v", err)
+            }
+            if d.DesiredReplicas != tt.want {
+                t.Errorf("DesiredReplicas = %d, want %d (reason: %s)", d.DesiredReplicas, tt.want, d.Reason)
+            }
+            if len(d.Vetoes) != tt.wantVetoes {
+                t.Errorf("Vetoes = %v, want %d", d.Vetoes, tt.wantVetoes)
+            }
+            if (d.HoldReason != "") != tt.wantHold {
+                t.Errorf("HoldReason = %q, want hold %v", d.HoldReason, tt.wantHold)
+            }
+        })
+    }
+}
+
+func TestDecideRejectsGateWithoutConfig(t *testing.T) {
+    gate := gateMetric("")
+    gate.Gate = nil
+    _, err := NewEngine().Decide(Input{
+        CurrentReplicas: 3,
+        Spec:            specWith(rpsMetric(), gate),
+        Samples:         map[string]float64{"rps": 150, "errors": 0.1},
+    })
+    if err == nil {
+        t.Fatal("Decide accepted a gate metric without gate configuration")
+    }
+}
+
+func TestApplyCooldownAndHistory(t *testing.T) {
+    now := time.Now()
+    recent := now.Add(-30 * time.Second)
+
+    tests := []struct {
+        name         string
+        behavior     autoscalerv1alpha1.BehaviorSpec
+        lastScale    *time.Time
+        history      []HistorySample
+        desired      int32
+        want         int32
+        wantCooldown bool
+    }{
+        {
+            name:         "scale up cooldown",
+            behavior:     autoscalerv1alpha1.BehaviorSpec{ScaleUpCooldownSeconds: int32Ptr(60)},
+            lastScale:    &recent,
+            desired:      15,
+            want:         10,
+            wantCooldown: true,
+        },
+        {
+            name:      "scale up cooldown expired",
+            behavior:  autoscalerv1alpha1.BehaviorSpec{ScaleUpCooldownSeconds: int32Ptr(10)},
+            lastScale: &recent,
+            desired:   15,
+            want:      15,
+        },
+        {
+            name:     "stabilization keeps the window maximum",
+            behavior: autoscalerv1alpha1.BehaviorSpec{StabilizationWindowSeconds: int32Ptr(300)},
+            history: []HistorySample{
+                {Timestamp: now.Add(-10 * time.Minute), DesiredReplicas: 12},
+                {Timestamp: now.Add(-time.Minute), DesiredReplicas: 8},
+            },
+            desired: 4,
+            want:    8,
+        },
+        {
+            name:     "scale up step limit",
+            behavior: autoscalerv1alpha1.BehaviorSpec{MaxScaleUpStepPercent: int32Ptr(20)},
+            desired:  20,
+            want:     12,
+        },
+        {
+            name:     "scale down step limit is at least one",
+            behavior: autoscalerv1alpha1.BehaviorSpec{MaxScaleDownStepPercent: int32Ptr(1)},
+            desired:  2,
+            want:     9,
+        },
+    }
+
+    e := &DefaultEngine{}
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            behavior := tt.behavior
+            in := Input{
+                CurrentReplicas: 10,
+                Spec:            autoscalerv1alpha1.PrometheusAutoscalerSpec{Behavior: &behavior},
+                Now:             now,
+                LastScaleTime:   tt.lastScale,
+                History:         tt.history,
+            }
+            got, cooldown := e.applyCooldownAndHistory(in, tt.desired)
+            if got != tt.want || cooldown != tt.wantCooldown {
+                t.Errorf("applyCooldownAndHistory = (%d, %v), want (%d, %v)", got, cooldown, tt.want, tt.wantCooldown)
+            }
+        })
+    }
+}
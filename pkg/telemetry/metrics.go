@@ -0,0 +1,162 @@
+package telemetry
+
+import (
+    "net/url"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// All series are prefixed with this namespace and registered on the
+// controller-runtime registry, so the manager's metrics endpoint serves them.
+const metricsNamespace = "prometheus_autoscaler"
+
+var autoscalerLabels = []string{"namespace", "name"}
+
+var (
+    currentReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+        Namespace: metricsNamespace,
+        Name:      "current_replicas",
+        Help:      "Replicas the target workload runs, as reported by its scale subresource.",
+    }, autoscalerLabels)
+
+    desiredReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+        Namespace: metricsNamespace,
+        Name:      "desired_replicas",
+        Help:      "Replicas the autoscaler last decided on.",
+    }, autoscalerLabels)
+
+    metricValue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+        Namespace: metricsNamespace,
+        Name:      "metric_value",
+        Help:      "Last sample of each metric seen by the policy engine.",
+    }, append(autoscalerLabels, "metric"))
+
+    metricDesiredReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+        Namespace: metricsNamespace,
+        Name:      "metric_desired_replicas",
+        Help:      "Replicas each metric voted for before aggregation, bounds and cooldowns.",
+    }, append(autoscalerLabels, "metric"))
+
+    cooldownActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+        Namespace: metricsNamespace,
+        Name:      "cooldown_active",
+        Help:      "1 while a scale-up or scale-down cooldown holds back the decision.",
+    }, autoscalerLabels)
+
+    scaleOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: metricsNamespace,
+        Name:      "scale_operations_total",
+        Help:      "Replica changes applied to the target, by direction.",
+    }, append(autoscalerLabels, "direction"))
+
+    evaluationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: metricsNamespace,
+        Name:      "evaluation_duration_seconds",
+        Help:      "Time spent evaluating an autoscaler, from reading the target to applying the decision.",
+        Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+    }, autoscalerLabels)
+
+    queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: metricsNamespace,
+        Name:      "query_duration_seconds",
+        Help:      "Latency of requests to Prometheus endpoints, per attempt.",
+        Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+    }, []string{"endpoint"})
+
+    queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: metricsNamespace,
+        Name:      "query_errors_total",
+        Help:      "Failed requests to Prometheus endpoints, per attempt.",
+    }, []string{"endpoint"})
+)
+
+func init() {
+    ctrlmetrics.Registry.MustRegister(
+        currentReplicas,
+        desiredReplicas,
+        metricValue,
+        metricDesiredReplicas,
+        cooldownActive,
+        scaleOperations,
+        evaluationDuration,
+        queryDuration,
+        queryErrors,
+    )
+}
+
+// ObserveReplicas records the current and desired replicas of an autoscaler.
+func ObserveReplicas(namespace, name string, current, desired int32) {
+    currentReplicas.WithLabelValues(namespace, name).Set(float64(current))
+    desiredReplicas.WithLabelValues(namespace, name).Set(float64(desired))
+}
+
+// ObserveDecision records the samples and per-metric votes of one decision.
+// Metrics missing from the maps (failed, gates, removed from the spec) lose
+// their series instead of keeping a stale value.
+func ObserveDecision(namespace, name string, samples map[string]float64, votes map[string]int32, cooldown bool) {
+    labels := prometheus.Labels{"namespace": namespace, "name": name}
+    metricValue.DeletePartialMatch(labels)
+    metricDesiredReplicas.DeletePartialMatch(labels)
+
+    for metric, v := range samples {
+        metricValue.WithLabelValues(namespace, name, metric).Set(v)
+    }
+    for metric, v := range votes {
+        metricDesiredReplicas.WithLabelValues(namespace, name, metric).Set(float64(v))
+    }
+
+    active := 0.0
+    if cooldown {
+        active = 1
+    }
+    cooldownActive.WithLabelValues(namespace, name).Set(active)
+}
+
+// RecordScale counts a replica change applied to the target.
+func RecordScale(namespace, name string, from, to int32) {
+    direction := "up"
+    if to < from {
+        direction = "down"
+    }
+    scaleOperations.WithLabelValues(namespace, name, direction).Inc()
+}
+
+// ObserveEvaluation records how long one evaluation took.
+func ObserveEvaluation(namespace, name string, d time.Duration) {
+    evaluationDuration.WithLabelValues(namespace, name).Observe(d.Seconds())
+}
+
+// ObserveQuery records one request to a Prometheus endpoint. Its signature
+// matches metrics.QueryObserver.
+func ObserveQuery(endpoint string, d time.Duration, err error) {
+    label := endpointLabel(endpoint)
+    queryDuration.WithLabelValues(label).Observe(d.Seconds())
+    if err != nil {
+        queryErrors.WithLabelValues(label).Inc()
+    }
+}
+
+// endpointLabel reduces an endpoint URL to scheme, host and path, so
+// credentials in userinfo or query parameters never reach the metrics
+// endpoint.
+func endpointLabel(endpoint string) string {
+    u, err := url.Parse(endpoint)
+    if err != nil {
+        return "invalid"
+    }
+    return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+}
+
+// Forget drops every series of a deleted autoscaler.
+func Forget(namespace, name string) {
+    labels := prometheus.Labels{"namespace": namespace, "name": name}
+    currentReplicas.DeletePartialMatch(labels)
+    desiredReplicas.DeletePartialMatch(labels)
+    metricValue.DeletePartialMatch(labels)
+    metricDesiredReplicas.DeletePartialMatch(labels)
+    cooldownActive.DeletePartialMatch(labels)
+    scaleOperations.DeletePartialMatch(labels)
+    evaluationDuration.DeletePartialMatch(labels)
+}